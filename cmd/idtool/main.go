@@ -0,0 +1,393 @@
+// Command idtool generates and inspects IDs from pkg/id.
+//
+// Usage:
+//
+//	idtool gen [-n count] [-version 1|2] [-node n] [-kind k] [-format f]
+//	idtool decode [-format text|json] ID...
+//	idtool validate [-tolerance d] [-strict=false] < ids.txt
+//	idtool range [-version 1|2] [-format f] FROM [TO]
+//
+// Every command accepts -epoch default|legacy|RFC3339 for IDs minted with
+// id.WithEpoch. IDs may be given with or without a kind prefix such as
+// "acct_".
+package main
+
+import (
+	"bufio"
+	"chariot-assessment/pkg/id"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const usage = `usage: idtool <command> [flags]
+
+commands:
+  gen       generate IDs
+  decode    print the parts of IDs
+  validate  check IDs read one per line from stdin
+  range     print the smallest and largest IDs for a time range
+
+run "idtool <command> -h" for the flags of each command
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command in args and returns the exit status
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	commands := map[string]func([]string, io.Reader, io.Writer) error{
+		"gen":      gen,
+		"decode":   decode,
+		"validate": validate,
+		"range":    timeRange,
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "idtool: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+	if err := cmd(args[1:], stdin, stdout); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(stderr, "idtool:", err)
+		var invalid errInvalidIDs
+		if errors.As(err, &invalid) {
+			return 1
+		}
+		return 2
+	}
+	return 0
+}
+
+// errInvalidIDs is returned by validate when any ID fails validation
+type errInvalidIDs int
+
+func (e errInvalidIDs) Error() string {
+	return fmt.Sprintf("%d invalid IDs", int(e))
+}
+
+// epochFlag parses -epoch values
+type epochFlag struct {
+	t time.Time
+}
+
+func (e *epochFlag) String() string {
+	if e.t.IsZero() {
+		return "default"
+	}
+	return e.t.Format(time.RFC3339)
+}
+
+func (e *epochFlag) Set(s string) error {
+	switch s {
+	case "default":
+		e.t = id.DefaultEpoch
+	case "legacy":
+		e.t = id.LegacyEpoch
+	default:
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return err
+		}
+		e.t = t
+	}
+	return nil
+}
+
+func (e *epochFlag) get() time.Time {
+	if e.t.IsZero() {
+		return id.DefaultEpoch
+	}
+	return e.t
+}
+
+func newFlagSet(name string, epoch *epochFlag) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Var(epoch, "epoch", "epoch IDs are counted from: default, legacy or an RFC 3339 time")
+	return fs
+}
+
+// parseID parses an ID in any form FromString accepts, with or without a
+// kind prefix, returning the kind if one was given
+func parseID(s string) (id.ID, id.Kind, error) {
+	if !strings.Contains(s, "_") {
+		parsed, err := id.FromString(s)
+		return parsed, "", err
+	}
+	kind, rest, err := id.SplitKind(s)
+	if err != nil {
+		return id.ID{}, "", err
+	}
+	parsed, err := id.FromString(rest)
+	return parsed, kind, err
+}
+
+// formatID writes id in the named format. UUIDs and ULIDs carry Unix time,
+// which can only be recovered from IDs counted from DefaultEpoch.
+func formatID(v id.ID, format string, kind id.Kind, epoch time.Time) (string, error) {
+	if (format == "uuid" || format == "ulid") && !epoch.Equal(id.DefaultEpoch) {
+		return "", fmt.Errorf("%s output requires the default epoch", format)
+	}
+	var s string
+	switch format {
+	case "std":
+		s = v.String()
+	case "crockford":
+		s = id.CrockfordEncoding.EncodeToString(v)
+	case "hex":
+		s = hex.EncodeToString(v.Bytes())
+	case "uuid":
+		s = v.UUID().String()
+	case "ulid":
+		s = v.ULID().String()
+	default:
+		return "", fmt.Errorf("unknown format %q: must be std, crockford, hex, uuid or ulid", format)
+	}
+	switch {
+	case kind == "":
+	case format == "std":
+		// as the API renders it, with its check symbol
+		s = kind.Format(v)
+	default:
+		s = kind.Prefix() + s
+	}
+	return s, nil
+}
+
+func gen(args []string, _ io.Reader, stdout io.Writer) error {
+	var epoch epochFlag
+	fs := newFlagSet("gen", &epoch)
+	n := fs.Int("n", 1, "number of IDs to generate")
+	version := fs.Int("version", 1, "ID format: 1 or 2")
+	node := fs.Int("node", -1, "node ID to embed, as with NODE_ID")
+	kind := fs.String("kind", "", "kind prefix, e.g. acct")
+	format := fs.String("format", "std", "output format: std, crockford, hex, uuid or ulid")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("gen: unexpected arguments %q", fs.Args())
+	}
+
+	opts := []id.Option{
+		id.WithVersion(id.Version(*version)),
+		id.WithEpoch(epoch.get()),
+		// bulk generation should not stall on the per-second counter
+		id.WithOverflowPolicy(id.OverflowBorrow),
+	}
+	if *node >= 0 {
+		// checked here, as converting to uint16 would wrap larger values
+		if *node > id.MaxNode {
+			return fmt.Errorf("gen: node %d out of range [0, %d]", *node, id.MaxNode)
+		}
+		opts = append(opts, id.WithNode(uint16(*node)))
+	}
+	g, err := id.NewGenerator(opts...)
+	if err != nil {
+		return err
+	}
+	ids, err := g.NewBatch(*n)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(stdout)
+	for _, next := range ids {
+		s, err := formatID(next, *format, id.Kind(*kind), epoch.get())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, s)
+	}
+	return w.Flush()
+}
+
+// decoded is the breakdown printed by decode
+type decoded struct {
+	Input   string     `json:"input"`
+	Kind    id.Kind    `json:"kind,omitempty"`
+	Version id.Version `json:"version"`
+	Time    time.Time  `json:"time"`
+	Counter uint32     `json:"counter"`
+	Node    uint16     `json:"node"`
+	Random  string     `json:"random"`
+	Hex     string     `json:"hex"`
+	UUID    string     `json:"uuid,omitempty"`
+	ULID    string     `json:"ulid,omitempty"`
+}
+
+func decode(args []string, _ io.Reader, stdout io.Writer) error {
+	var epoch epochFlag
+	fs := newFlagSet("decode", &epoch)
+	format := fs.String("format", "text", "output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("decode: no IDs given")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q: must be text or json", *format)
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	for i, s := range fs.Args() {
+		v, kind, err := parseID(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", s, err)
+		}
+		d := decoded{
+			Input:   s,
+			Kind:    kind,
+			Version: v.Version(),
+			Time:    v.TimeFrom(epoch.get()),
+			Counter: v.Counter(),
+			Node:    v.Node(),
+			Random:  hex.EncodeToString(v.Entropy()),
+			Hex:     hex.EncodeToString(v.Bytes()),
+		}
+		if epoch.get().Equal(id.DefaultEpoch) {
+			d.UUID, d.ULID = v.UUID().String(), v.ULID().String()
+		}
+		if *format == "json" {
+			if err := enc.Encode(d); err != nil {
+				return err
+			}
+			continue
+		}
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		fmt.Fprintf(stdout, "id:       %s\n", d.Input)
+		if d.Kind != "" {
+			fmt.Fprintf(stdout, "kind:     %s\n", d.Kind)
+		}
+		fmt.Fprintf(stdout, "version:  %d\n", d.Version)
+		fmt.Fprintf(stdout, "time:     %s (%s ago)\n", d.Time.Format(time.RFC3339), time.Since(d.Time).Round(time.Second))
+		fmt.Fprintf(stdout, "counter:  %d\n", d.Counter)
+		fmt.Fprintf(stdout, "node:     %d (only meaningful with NODE_ID)\n", d.Node)
+		fmt.Fprintf(stdout, "random:   %s\n", d.Random)
+		fmt.Fprintf(stdout, "hex:      %s\n", d.Hex)
+		if d.UUID != "" {
+			fmt.Fprintf(stdout, "uuid:     %s\n", d.UUID)
+			fmt.Fprintf(stdout, "ulid:     %s\n", d.ULID)
+		}
+	}
+	return nil
+}
+
+func validate(args []string, stdin io.Reader, stdout io.Writer) error {
+	var epoch epochFlag
+	fs := newFlagSet("validate", &epoch)
+	tolerance := fs.Duration("tolerance", 5*time.Second, "how far in the future timestamps may be")
+	strict := fs.Bool("strict", true, "require the canonical string form")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	v := &id.Validator{Epoch: epoch.get(), FutureTolerance: *tolerance}
+
+	var invalid errInvalidIDs
+	scanner := bufio.NewScanner(stdin)
+	for line := 1; scanner.Scan(); line++ {
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" {
+			continue
+		}
+		if err := validateOne(v, s, *strict); err != nil {
+			fmt.Fprintf(stdout, "%d: %s: %v\n", line, s, err)
+			invalid++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if invalid > 0 {
+		return invalid
+	}
+	return nil
+}
+
+// validateOne validates s, which may carry a kind prefix
+func validateOne(v *id.Validator, s string, strict bool) error {
+	if !strict {
+		parsed, _, err := parseID(s)
+		if err != nil {
+			return err
+		}
+		return v.Validate(parsed)
+	}
+	if strings.Contains(s, "_") {
+		kind, _, err := id.SplitKind(s)
+		if err != nil {
+			return err
+		}
+		_, err = v.ParseKind(kind, s)
+		return err
+	}
+	_, err := v.Parse(s)
+	return err
+}
+
+func timeRange(args []string, _ io.Reader, stdout io.Writer) error {
+	var epoch epochFlag
+	fs := newFlagSet("range", &epoch)
+	version := fs.Int("version", 1, "ID format: 1 or 2")
+	format := fs.String("format", "sql", "output format: sql (bytea literals), std, crockford, hex, uuid or ulid")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: idtool range [flags] FROM [TO]\n\nFROM and TO are RFC 3339 times; TO defaults to FROM.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return errors.New("range: expected FROM [TO]")
+	}
+	from, err := time.Parse(time.RFC3339, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	to := from
+	if fs.NArg() == 2 {
+		if to, err = time.Parse(time.RFC3339, fs.Arg(1)); err != nil {
+			return err
+		}
+	}
+	if to.Before(from) {
+		return errors.New("range: TO is before FROM")
+	}
+
+	// a throwaway generator resolves bounds for the version and epoch
+	g, err := id.NewGenerator(id.WithVersion(id.Version(*version)), id.WithEpoch(epoch.get()))
+	if err != nil {
+		return err
+	}
+	min, max := g.MinForTime(from), g.MaxForTime(to)
+	if *format == "sql" {
+		// bounds only hold where IDs are compared bytewise, i.e. bytea
+		fmt.Fprintf(stdout, "id BETWEEN '\\x%s'::bytea AND '\\x%s'::bytea\n", hex.EncodeToString(min.Bytes()), hex.EncodeToString(max.Bytes()))
+		return nil
+	}
+	for _, bound := range []id.ID{min, max} {
+		s, err := formatID(bound, *format, "", epoch.get())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, s)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"chariot-assessment/pkg/id"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runTool runs idtool with args and stdin, returning its exit status and
+// output
+func runTool(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestGen(t *testing.T) {
+	code, out, errOut := runTool(t, "", "gen", "-n", "100", "-version", "2", "-kind", "txn")
+	if code != 0 {
+		t.Fatalf("got exit status %d: %s", code, errOut)
+	}
+	lines := strings.Fields(out)
+	if len(lines) != 100 {
+		t.Fatalf("got %d IDs, want 100", len(lines))
+	}
+	var last id.ID
+	for _, s := range lines {
+		next, err := id.ParseTransactionID(s)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: got error %v", s, err)
+		}
+		if s != id.KindTransaction.Format(id.ID(next)) {
+			t.Fatalf("got %q, want the API form %q", s, id.KindTransaction.Format(id.ID(next)))
+		}
+		if id.ID(next).Version() != id.V2 {
+			t.Fatalf("got version %d, want 2", id.ID(next).Version())
+		}
+		if bytes.Compare(last[:], next[:]) >= 0 {
+			t.Fatalf("Next ID not monotonic: (last, new) = (%v, %v)", last, next)
+		}
+		last = id.ID(next)
+	}
+
+	if code, _, _ := runTool(t, "", "gen", "-format", "uuid", "-epoch", "legacy"); code != 2 {
+		t.Fatalf("got exit status %d, want 2 for UUIDs from the legacy epoch", code)
+	}
+	for _, node := range []string{strconv.Itoa(id.MaxNode + 1), "65536"} {
+		if code, out, _ := runTool(t, "", "gen", "-node", node); code != 2 || out != "" {
+			t.Fatalf("-node %s: got exit status %d and %q, want 2 and no IDs", node, code, out)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	code, out, errOut := runTool(t, "", "decode", "-format", "json", "acct_BC5YUGA-AATBBDEINAA")
+	if code != 0 {
+		t.Fatalf("got exit status %d: %s", code, errOut)
+	}
+	var got decoded
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("Failed to unmarshal %s: got error %v", out, err)
+	}
+	want := decoded{
+		Input:   "acct_BC5YUGA-AATBBDEINAA",
+		Kind:    id.KindAccount,
+		Version: id.V1,
+		Time:    time.Date(2024, 8, 22, 16, 45, 12, 0, time.UTC),
+		Counter: 4,
+		Node:    776,
+		Random:  "c211910d00",
+		Hex:     "08bb8a180004c211910d00",
+		UUID:    "01917afa-55c0-7000-8013-084644340000",
+		ULID:    "01J5XFMNE000009GGHJ46G0000",
+	}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	code, out, _ = runTool(t, "", "decode", "-epoch", "legacy", "BC5YUGA-AATBBDEINAA")
+	if code != 0 || !strings.Contains(out, "time:     2024-08-22T21:45:12Z") {
+		t.Fatalf("got exit status %d and output %q, want the legacy time", code, out)
+	}
+	if code, _, _ := runTool(t, "", "decode", "BC5YUGA"); code != 2 {
+		t.Fatalf("got exit status %d, want 2", code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	good, err := id.New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	in := strings.Join([]string{
+		good.String(),
+		"",
+		id.KindAccount.Format(good),
+		strings.ToLower(good.String()),
+		"7777777-777777777777",
+	}, "\n")
+	code, out, _ := runTool(t, in, "validate")
+	if code != 1 {
+		t.Fatalf("got exit status %d, want 1", code)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "4: ") || !strings.HasPrefix(lines[1], "5: ") {
+		t.Fatalf("got output %q, want lines 4 and 5 reported", out)
+	}
+
+	if code, out, _ := runTool(t, strings.ToLower(good.String()), "validate", "-strict=false"); code != 0 {
+		t.Fatalf("got exit status %d and output %q, want 0", code, out)
+	}
+}
+
+func TestRange(t *testing.T) {
+	code, out, errOut := runTool(t, "", "range", "-format", "std", "2024-08-22T16:45:12Z")
+	if code != 0 {
+		t.Fatalf("got exit status %d: %s", code, errOut)
+	}
+	bounds := strings.Fields(out)
+	if len(bounds) != 2 {
+		t.Fatalf("got %q, want two bounds", out)
+	}
+	known, _ := id.FromString("BC5YUGA-AATBBDEINAA")
+	min, _ := id.FromString(bounds[0])
+	max, _ := id.FromString(bounds[1])
+	if bytes.Compare(min[:], known[:]) > 0 || bytes.Compare(known[:], max[:]) > 0 {
+		t.Fatalf("%v not within [%v, %v]", known, min, max)
+	}
+
+	code, out, _ = runTool(t, "", "range", "2024-08-22T16:00:00Z", "2024-08-22T17:00:00Z")
+	if want := "id BETWEEN '\\x08bb7f8000000000000000'::bytea AND '\\x08bb8d90ffffffffffffff'::bytea\n"; code != 0 || out != want {
+		t.Fatalf("got %d, %q, want 0, %q", code, out, want)
+	}
+	if code, _, _ := runTool(t, "", "range", "2024-08-22T17:00:00Z", "2024-08-22T16:00:00Z"); code != 2 {
+		t.Fatalf("got exit status %d, want 2", code)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	if code, _, errOut := runTool(t, ""); code != 2 || !strings.Contains(errOut, "usage") {
+		t.Fatalf("got %d, %q, want usage", code, errOut)
+	}
+	if code, _, _ := runTool(t, "", "frobnicate"); code != 2 {
+		t.Fatalf("got exit status %d, want 2", code)
+	}
+}
@@ -1,16 +1,210 @@
 package main
 
 import (
+	"chariot-assessment/pkg/id"
+	"chariot-assessment/pkg/money"
+	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
 )
 
+// idColumnType returns the column type used for IDs in the configured
+// storage format
+func idColumnType() string {
+	if id.GetStorageFormat() == id.StorageBinary {
+		// 11 bytes for V1 IDs, 12 for V2
+		return "bytea"
+	}
+	return "varchar(20)"
+}
+
+// idArray wraps ids for use as a Postgres array parameter, which is NULL
+// if there are none. pq encodes []byte elements of a generic array as raw
+// text, so binary IDs need a ByteaArray.
+func idArray(ids []id.AccountID) driver.Valuer {
+	if len(ids) == 0 {
+		// an empty array is not NULL, and would match nothing
+		return nil
+	}
+	if id.GetStorageFormat() != id.StorageBinary {
+		return pq.Array(ids)
+	}
+	arr := make(pq.ByteaArray, len(ids))
+	for i, accountID := range ids {
+		arr[i] = id.ID(accountID).Bytes()
+	}
+	return arr
+}
+
+// idFunctions are SQL counterparts of pkg/id used by migrations.
+//
+// chariot_id_to_bytea decodes the StdEncoding string form of an ID into its
+// byte form: 18 Base32 characters become the 11 bytes of a V1 ID, and 19
+// characters become the 95 bits of a V2 ID followed by its version bit.
+//
+// chariot_id_created_at computes ID.OrderedTimeFrom for the byte form of an
+// ID, given the Unix time of its epoch.
+const idFunctions = `
+    CREATE OR REPLACE FUNCTION chariot_id_to_bytea(s text) RETURNS bytea AS $$
+    DECLARE
+        symbols text := replace(upper(s), '-', '');
+        result bytea := '\x'::bytea;
+        buf int := 0;
+        bits int := 0;
+        v int;
+    BEGIN
+        FOR i IN 1..length(symbols) LOOP
+            v := strpos('ABCDEFGHIJKLMNOPQRSTUVWXYZ234567', substr(symbols, i, 1)) - 1;
+            IF v < 0 THEN
+                RAISE EXCEPTION 'invalid ID: %', s;
+            END IF;
+            buf := (buf << 5) | v;
+            bits := bits + 5;
+            IF bits >= 8 THEN
+                bits := bits - 8;
+                result := result || set_byte('\x00'::bytea, 0, buf >> bits);
+                buf := buf & ((1 << bits) - 1);
+            END IF;
+        END LOOP;
+        IF length(symbols) = 19 THEN
+            result := result || set_byte('\x00'::bytea, 0, (buf << (8 - bits)) | 1);
+        END IF;
+        RETURN result;
+    END;
+    $$ LANGUAGE plpgsql IMMUTABLE STRICT;
+
+    CREATE OR REPLACE FUNCTION chariot_id_created_at(b bytea, epoch bigint) RETURNS timestamp AS $$
+        SELECT to_timestamp(epoch + ((get_byte(b, 0)::bigint << 24) | (get_byte(b, 1) << 16)
+                | (get_byte(b, 2) << 8) | get_byte(b, 3))) AT TIME ZONE 'UTC'
+            + interval '1 microsecond' * CASE WHEN length(b) = 12
+                THEN ((get_byte(b, 4)::bigint << 16) | (get_byte(b, 5) << 8) | get_byte(b, 6)) * 1000000 >> 24
+                ELSE ((get_byte(b, 4)::bigint << 8) | get_byte(b, 5)) * 1000000 >> 16
+            END
+    $$ LANGUAGE sql IMMUTABLE STRICT;
+`
+
+// ledgerTriggers enforce the double-entry ledger (see ledger.go) in the
+// database, whatever writes to it.
+//
+// chariot_post_balance rejects postings in a currency other than their
+// account's, and adds each posting to its account's balance, so that
+// accounts.balance is a projection of the postings. System accounts are
+// not projected: every deposit and withdrawal in a currency posts to the
+// same cash account, and updating its row would serialize them all. Their
+// balance is the sum of their postings.
+//
+// chariot_check_entry_balanced runs when the transaction commits, once
+// every posting of an entry has been inserted, and rejects entries whose
+// postings do not sum to zero in each currency.
+//
+// chariot_append_only rejects updates and deletes: a mistaken entry is
+// corrected by posting another that reverses it.
+const ledgerTriggers = `
+    CREATE OR REPLACE FUNCTION chariot_post_balance() RETURNS trigger AS $$
+    DECLARE
+        account_currency char(3);
+        is_system boolean;
+    BEGIN
+        SELECT currency, system_account IS NOT NULL INTO account_currency, is_system
+            FROM accounts WHERE id = NEW.account_id;
+        IF account_currency <> NEW.currency THEN
+            RAISE EXCEPTION 'posting in % to an account in %', NEW.currency, account_currency
+                USING ERRCODE = 'check_violation';
+        END IF;
+        IF NOT is_system THEN
+            UPDATE accounts SET balance = balance + NEW.amount WHERE id = NEW.account_id;
+        END IF;
+        RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
+
+    CREATE OR REPLACE FUNCTION chariot_check_entry_balanced() RETURNS trigger AS $$
+    DECLARE
+        unbalanced char(3);
+    BEGIN
+        SELECT currency INTO unbalanced FROM postings
+            WHERE journal_entry_id = NEW.journal_entry_id
+            GROUP BY currency
+            HAVING sum(amount) <> 0
+            LIMIT 1;
+        IF FOUND THEN
+            RAISE EXCEPTION 'journal entry postings in % do not sum to zero', unbalanced
+                USING ERRCODE = 'check_violation';
+        END IF;
+        RETURN NULL;
+    END;
+    $$ LANGUAGE plpgsql;
+
+    CREATE OR REPLACE FUNCTION chariot_append_only() RETURNS trigger AS $$
+    BEGIN
+        RAISE EXCEPTION '% is append-only', TG_TABLE_NAME
+            USING ERRCODE = 'check_violation';
+    END;
+    $$ LANGUAGE plpgsql;
+
+    DO $$ BEGIN
+        IF NOT EXISTS (SELECT 1 FROM pg_trigger WHERE tgrelid = 'postings'::regclass AND tgname = 'postings_balance') THEN
+            CREATE TRIGGER postings_balance AFTER INSERT ON postings
+                FOR EACH ROW EXECUTE PROCEDURE chariot_post_balance();
+        END IF;
+        IF NOT EXISTS (SELECT 1 FROM pg_trigger WHERE tgrelid = 'postings'::regclass AND tgname = 'postings_balanced') THEN
+            CREATE CONSTRAINT TRIGGER postings_balanced AFTER INSERT ON postings
+                DEFERRABLE INITIALLY DEFERRED
+                FOR EACH ROW EXECUTE PROCEDURE chariot_check_entry_balanced();
+        END IF;
+        IF NOT EXISTS (SELECT 1 FROM pg_trigger WHERE tgrelid = 'postings'::regclass AND tgname = 'postings_append_only') THEN
+            CREATE TRIGGER postings_append_only BEFORE UPDATE OR DELETE ON postings
+                FOR EACH ROW EXECUTE PROCEDURE chariot_append_only();
+        END IF;
+        IF NOT EXISTS (SELECT 1 FROM pg_trigger WHERE tgrelid = 'journal_entries'::regclass AND tgname = 'journal_entries_append_only') THEN
+            CREATE TRIGGER journal_entries_append_only BEFORE UPDATE OR DELETE ON journal_entries
+                FOR EACH ROW EXECUTE PROCEDURE chariot_append_only();
+        END IF;
+    END $$;
+`
+
+// idCreatedAt returns the created_at value of the row keyed by v. Deriving
+// it from the ID, rather than the database's clock, keeps ordering by
+// created_at consistent with ordering by ID.
+func idCreatedAt(v id.ID) time.Time {
+	return v.OrderedTimeFrom(idGenerator.Epoch())
+}
+
+// idBytes returns a SQL expression for the byte form of the ID column col
+func idBytes(col string) string {
+	if id.GetStorageFormat() == id.StorageBinary {
+		return col
+	}
+	return "chariot_id_to_bytea(" + col + ")"
+}
+
 func CreateSchema() error {
 	if pgClient == nil {
 		return errors.New("postgres client has not been initialized.")
 	}
-	// balances use a decimal precision of 15,4
-	// for forward-compatibility w/ other currencies
-	_, err := pgClient.Exec(`
+	if _, err := pgClient.Exec(idFunctions); err != nil {
+		return fmt.Errorf("Error creating ID functions: %w", err)
+	}
+
+	// tables added to an existing database match its current ID columns,
+	// which MigrateIDStorage then converts along with the rest
+	columnType, err := schemaIDColumnType()
+	if err != nil {
+		return err
+	}
+
+	// balances use a decimal precision of 15,4, enough for the minor units
+	// of every currency (see money.Currency). Transactions record the
+	// currency of their account, which may differ between transfer legs,
+	// and the exchange rate applied to cross-currency transfers.
+	// created_at has no default: inserts set it with idCreatedAt.
+	// System accounts have no user_id; their constraints are added by
+	// MigrateLedger, which also upgrades older databases.
+	_, err = pgClient.Exec(fmt.Sprintf(`
         DO $$ BEGIN
             CREATE TYPE t_transaction AS ENUM
                 ('withdrawal', 'deposit', 'transfer_in', 'transfer_out');
@@ -19,33 +213,432 @@ func CreateSchema() error {
         END $$;
 
         CREATE TABLE IF NOT EXISTS users(
-            id varchar(20) PRIMARY KEY,
+            id %[1]s PRIMARY KEY,
             name text,
-            created_at timestamp DEFAULT current_timestamp
+            created_at timestamp NOT NULL
         );
         CREATE TABLE IF NOT EXISTS accounts(
-            id varchar(20) PRIMARY KEY,
-            user_id varchar(20) NOT NULL,
+            id %[1]s PRIMARY KEY,
+            user_id %[1]s,
             balance decimal(15,4) NOT NULL DEFAULT 0.0,
-            created_at timestamp DEFAULT current_timestamp,
+            currency char(3) NOT NULL,
+            system_account varchar(20),
+            created_at timestamp NOT NULL,
             FOREIGN KEY (user_id) REFERENCES users(id)
         );
+        CREATE TABLE IF NOT EXISTS fx_quotes(
+            id %[1]s PRIMARY KEY,
+            from_currency char(3) NOT NULL,
+            to_currency char(3) NOT NULL,
+            rate decimal(18,8) NOT NULL,
+            created_at timestamp NOT NULL,
+            expires_at timestamp NOT NULL
+        );
+        CREATE TABLE IF NOT EXISTS journal_entries(
+            id %[1]s PRIMARY KEY,
+            created_at timestamp NOT NULL
+        );
+        CREATE TABLE IF NOT EXISTS postings(
+            journal_entry_id %[1]s NOT NULL,
+            line smallint NOT NULL,
+            account_id %[1]s NOT NULL,
+            amount decimal(15,4) NOT NULL CHECK (amount <> 0),
+            currency char(3) NOT NULL,
+            PRIMARY KEY (journal_entry_id, line),
+            FOREIGN KEY (journal_entry_id) REFERENCES journal_entries(id),
+            FOREIGN KEY (account_id) REFERENCES accounts(id)
+        );
+        CREATE INDEX IF NOT EXISTS postings_account_id_idx ON postings(account_id);
         CREATE TABLE IF NOT EXISTS transactions(
-            id varchar(20) PRIMARY KEY,
-            related_transaction_id varchar(20),
-            account_id varchar(20) NOT NULL,
-            external_account varchar(20),
+            id %[1]s PRIMARY KEY,
+            related_transaction_id %[1]s,
+            account_id %[1]s NOT NULL,
+            external_account %[1]s,
             idempotency_key varchar(100) NOT NULL,
             amount decimal(15,4) NOT NULL,
+            currency char(3) NOT NULL,
             ending_balance decimal(15,4) NOT NULL,
             type t_transaction,
-            created_at timestamp DEFAULT current_timestamp,
+            created_at timestamp NOT NULL,
             FOREIGN KEY (account_id) REFERENCES accounts(id),
             FOREIGN KEY (external_account) REFERENCES accounts(id),
             FOREIGN KEY (related_transaction_id) REFERENCES transactions(id),
             UNIQUE (idempotency_key, type)
         );
+        ALTER TABLE transactions
+            ADD COLUMN IF NOT EXISTS fx_rate decimal(18,8),
+            ADD COLUMN IF NOT EXISTS fx_quote_id %[1]s REFERENCES fx_quotes(id),
+            ADD COLUMN IF NOT EXISTS journal_entry_id %[1]s REFERENCES journal_entries(id);
+    `, columnType))
+	if err != nil {
+		return err
+	}
+
+	if _, err := pgClient.Exec(ledgerTriggers); err != nil {
+		return fmt.Errorf("Error creating ledger triggers: %w", err)
+	}
+	return nil
+}
+
+// schemaIDColumnType returns the type of the ID columns already in the
+// database, or the type for the configured storage format if there are none
+func schemaIDColumnType() (string, error) {
+	var dataType string
+	err := pgClient.QueryRow(`
+		SELECT data_type FROM information_schema.columns
+		WHERE table_schema = current_schema()
+		AND table_name = 'users' AND column_name = 'id'
+	`).Scan(&dataType)
+	if err == sql.ErrNoRows {
+		return idColumnType(), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("Error checking ID column type: %w", err)
+	}
+	if dataType == "bytea" {
+		return "bytea", nil
+	}
+	return "varchar(20)", nil
+}
+
+// MigrateIDStorage converts the ID columns of a database created with
+// varchar IDs to bytea when binary storage is configured. It is a no-op if
+// the columns already match the configured format, and fails if they are
+// bytea but text storage is configured. The conversion runs in a
+// single transaction, rewriting every table, so it should be run during a
+// maintenance window on large databases.
+func MigrateIDStorage() error {
+	if pgClient == nil {
+		return errors.New("postgres client has not been initialized.")
+	}
+	columnType, err := schemaIDColumnType()
+	if err != nil {
+		return err
+	}
+	if id.GetStorageFormat() != id.StorageBinary {
+		// text IDs sent to bytea columns would match no rows
+		if columnType == "bytea" {
+			return errors.New("ID columns are bytea, but the configured ID storage is text: set ID_STORAGE=binary, as migrating back to varchar is not supported")
+		}
+		return nil
+	}
+	if columnType == "bytea" {
+		return nil
+	}
+
+	tx, err := pgClient.Begin()
+	if err != nil {
+		return fmt.Errorf("Could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+        ALTER TABLE accounts DROP CONSTRAINT accounts_user_id_fkey;
+        ALTER TABLE transactions
+            DROP CONSTRAINT transactions_account_id_fkey,
+            DROP CONSTRAINT transactions_external_account_fkey,
+            DROP CONSTRAINT transactions_related_transaction_id_fkey,
+            DROP CONSTRAINT transactions_fx_quote_id_fkey,
+            DROP CONSTRAINT transactions_journal_entry_id_fkey;
+        ALTER TABLE postings
+            DROP CONSTRAINT postings_journal_entry_id_fkey,
+            DROP CONSTRAINT postings_account_id_fkey;
+
+        ALTER TABLE users
+            ALTER COLUMN id TYPE bytea USING chariot_id_to_bytea(id);
+        ALTER TABLE accounts
+            ALTER COLUMN id TYPE bytea USING chariot_id_to_bytea(id),
+            ALTER COLUMN user_id TYPE bytea USING chariot_id_to_bytea(user_id);
+        ALTER TABLE transactions
+            ALTER COLUMN id TYPE bytea USING chariot_id_to_bytea(id),
+            ALTER COLUMN related_transaction_id TYPE bytea USING chariot_id_to_bytea(related_transaction_id),
+            ALTER COLUMN account_id TYPE bytea USING chariot_id_to_bytea(account_id),
+            ALTER COLUMN external_account TYPE bytea USING chariot_id_to_bytea(external_account),
+            ALTER COLUMN fx_quote_id TYPE bytea USING chariot_id_to_bytea(fx_quote_id),
+            ALTER COLUMN journal_entry_id TYPE bytea USING chariot_id_to_bytea(journal_entry_id);
+        ALTER TABLE fx_quotes
+            ALTER COLUMN id TYPE bytea USING chariot_id_to_bytea(id);
+        ALTER TABLE journal_entries
+            ALTER COLUMN id TYPE bytea USING chariot_id_to_bytea(id);
+        ALTER TABLE postings
+            ALTER COLUMN journal_entry_id TYPE bytea USING chariot_id_to_bytea(journal_entry_id),
+            ALTER COLUMN account_id TYPE bytea USING chariot_id_to_bytea(account_id);
+
+        ALTER TABLE accounts
+            ADD FOREIGN KEY (user_id) REFERENCES users(id);
+        ALTER TABLE transactions
+            ADD FOREIGN KEY (account_id) REFERENCES accounts(id),
+            ADD FOREIGN KEY (external_account) REFERENCES accounts(id),
+            ADD FOREIGN KEY (related_transaction_id) REFERENCES transactions(id),
+            ADD FOREIGN KEY (fx_quote_id) REFERENCES fx_quotes(id),
+            ADD FOREIGN KEY (journal_entry_id) REFERENCES journal_entries(id);
+        ALTER TABLE postings
+            ADD FOREIGN KEY (journal_entry_id) REFERENCES journal_entries(id),
+            ADD FOREIGN KEY (account_id) REFERENCES accounts(id);
     `)
+	if err != nil {
+		return fmt.Errorf("Error migrating ID columns to bytea: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// createdAtTolerance is how far the created_at MigrateCreatedAt finds in a
+// row may be from the time derived from its ID. V1 IDs only hold whole
+// seconds, and the database's clock may differ slightly from the service's.
+const createdAtTolerance = 5 * time.Second
+
+// MigrateCreatedAt drops the default of the created_at columns of a database
+// created while they defaulted to the database's clock, so that every insert
+// must supply it, and rewrites every value as the time derived from the
+// row's ID. It refuses to run if existing values are further from those
+// times than createdAtTolerance, which means the configured ID epoch is not
+// the one the IDs were issued with, or the database's clock was not in UTC.
+// Tables that have already been migrated are skipped without being scanned.
+func MigrateCreatedAt() error {
+	if pgClient == nil {
+		return errors.New("postgres client has not been initialized.")
+	}
+	tx, err := pgClient.Begin()
+	if err != nil {
+		return fmt.Errorf("Could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	epoch := idGenerator.Epoch().Unix()
+	for _, table := range []string{"users", "accounts", "transactions"} {
+		var pending bool
+		err = tx.QueryRow(`
+			SELECT column_default IS NOT NULL OR is_nullable = 'YES'
+			FROM information_schema.columns
+			WHERE table_schema = current_schema()
+			AND table_name = $1 AND column_name = 'created_at'
+		`, table).Scan(&pending)
+		if err != nil {
+			return fmt.Errorf("Error checking %s.created_at: %w", table, err)
+		}
+		if !pending {
+			continue
+		}
+
+		var (
+			mismatched int
+			maxOffset  float64
+		)
+		err = tx.QueryRow(fmt.Sprintf(`
+			SELECT count(*), coalesce(max(offset_seconds), 0) FROM (
+				SELECT abs(extract(epoch FROM created_at - chariot_id_created_at(%[2]s, $1))) AS offset_seconds
+				FROM %[1]s
+				WHERE created_at IS NOT NULL
+			) offsets
+			WHERE offset_seconds > $2
+		`, table, idBytes("id")), epoch, createdAtTolerance.Seconds()).Scan(&mismatched, &maxOffset)
+		if err != nil {
+			return fmt.Errorf("Error comparing %s.created_at with IDs: %w", table, err)
+		}
+		if mismatched > 0 {
+			return fmt.Errorf("%d rows of %s were created up to %v away from the time in their IDs: "+
+				"the configured ID epoch %v is probably wrong (databases created before it was configurable need ID_EPOCH=legacy), "+
+				"or the database's TimeZone was not UTC when they were created",
+				mismatched, table, time.Duration(maxOffset)*time.Second, idGenerator.Epoch())
+		}
+
+		_, err = tx.Exec(fmt.Sprintf(`
+			UPDATE %[1]s SET created_at = chariot_id_created_at(%[2]s, $1)
+			WHERE created_at IS DISTINCT FROM chariot_id_created_at(%[2]s, $1)
+		`, table, idBytes("id")), epoch)
+		if err != nil {
+			return fmt.Errorf("Error deriving %s.created_at from IDs: %w", table, err)
+		}
+		_, err = tx.Exec(fmt.Sprintf(`
+			ALTER TABLE %s
+				ALTER COLUMN created_at DROP DEFAULT,
+				ALTER COLUMN created_at SET NOT NULL
+		`, table))
+		if err != nil {
+			return fmt.Errorf("Error altering %s.created_at: %w", table, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// MigrateCurrency adds the currency columns to a database created before
+// accounts had currencies. Existing accounts, and their transactions, are
+// assumed to be in USD. It is a no-op once the columns exist.
+func MigrateCurrency() error {
+	if pgClient == nil {
+		return errors.New("postgres client has not been initialized.")
+	}
+	tx, err := pgClient.Begin()
+	if err != nil {
+		return fmt.Errorf("Could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		ALTER TABLE accounts ADD COLUMN IF NOT EXISTS currency char(3) NOT NULL DEFAULT 'USD';
+		ALTER TABLE accounts ALTER COLUMN currency DROP DEFAULT;
+
+		ALTER TABLE transactions ADD COLUMN IF NOT EXISTS currency char(3);
+		UPDATE transactions t SET currency = a.currency
+			FROM accounts a
+			WHERE t.account_id = a.id AND t.currency IS NULL;
+		ALTER TABLE transactions ALTER COLUMN currency SET NOT NULL;
+	`)
+	if err != nil {
+		return fmt.Errorf("Error adding currency columns: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// MigrateLedger adds system accounts to a database created before the
+// double-entry ledger, and posts a journal entry for every transaction
+// recorded before it, in the same form as new ones (see ledger.go). The
+// account balances kept until then must equal the sum of the backfilled
+// postings, or the migration fails. After the first run it only checks the
+// balances.
+func MigrateLedger() error {
+	if pgClient == nil {
+		return errors.New("postgres client has not been initialized.")
+	}
+	tx, err := pgClient.Begin()
+	if err != nil {
+		return fmt.Errorf("Could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		ALTER TABLE accounts
+			ADD COLUMN IF NOT EXISTS system_account varchar(20),
+			ALTER COLUMN user_id DROP NOT NULL;
+		CREATE UNIQUE INDEX IF NOT EXISTS accounts_system_account_key
+			ON accounts(system_account, currency);
+		DO $$ BEGIN
+			ALTER TABLE accounts
+				ADD CONSTRAINT accounts_owner_check
+					CHECK ((user_id IS NULL) <> (system_account IS NULL)),
+				ADD CONSTRAINT accounts_balance_check
+					CHECK (system_account IS NOT NULL OR balance >= 0);
+		EXCEPTION
+			WHEN duplicate_object THEN null;
+		END $$;
+	`)
+	if err != nil {
+		return fmt.Errorf("Error adding system accounts: %w", err)
+	}
+
+	rows, err := tx.Query(`SELECT DISTINCT currency FROM accounts WHERE system_account IS NULL`)
+	if err != nil {
+		return fmt.Errorf("Error reading account currencies: %w", err)
+	}
+	var currencies []money.Currency
+	for rows.Next() {
+		var currency money.Currency
+		if err := rows.Scan(&currency); err != nil {
+			rows.Close()
+			return fmt.Errorf("Error reading account currencies: %w", err)
+		}
+		currencies = append(currencies, currency)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("Error reading account currencies: %w", err)
+	}
+	for _, currency := range currencies {
+		if err := ensureSystemAccounts(tx, currency); err != nil {
+			return err
+		}
+	}
+
+	// Toggling the trigger locks postings, so it is only done while
+	// transactions remain to be backfilled or the column is not yet NOT NULL
+	var pending bool
+	err = tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM transactions WHERE journal_entry_id IS NULL)
+		OR EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema()
+			AND table_name = 'transactions' AND column_name = 'journal_entry_id'
+			AND is_nullable = 'YES'
+		)
+	`).Scan(&pending)
+	if err != nil {
+		return fmt.Errorf("Error checking for transactions without journal entries: %w", err)
+	}
+	if pending {
+		if err := backfillJournalEntries(tx); err != nil {
+			return err
+		}
+	}
 
-	return err
+	var mismatched int
+	err = tx.QueryRow(`
+		SELECT count(*) FROM accounts a
+		WHERE a.system_account IS NULL
+		AND a.balance <> (SELECT coalesce(sum(p.amount), 0) FROM postings p WHERE p.account_id = a.id)
+	`).Scan(&mismatched)
+	if err != nil {
+		return fmt.Errorf("Error checking account balances: %w", err)
+	}
+	if mismatched > 0 {
+		return fmt.Errorf("%d account balances do not match their postings", mismatched)
+	}
+
+	return tx.Commit()
+}
+
+// backfillJournalEntries posts journal entries for the transactions recorded
+// before the ledger existed. The balances of their accounts already include
+// them, so they are not projected again. Transactions of zero, which were
+// once accepted, get an entry without postings. Each entry is numbered like those
+// of cashPostings and transferPostings, and transfers between currencies are
+// those with an fx_rate.
+func backfillJournalEntries(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		ALTER TABLE postings DISABLE TRIGGER postings_balance;
+
+		INSERT INTO journal_entries(id, created_at)
+			SELECT id, created_at FROM transactions
+			WHERE journal_entry_id IS NULL AND type <> 'transfer_in';
+
+		INSERT INTO postings(journal_entry_id, line, account_id, amount, currency)
+			SELECT CASE type WHEN 'transfer_in' THEN related_transaction_id ELSE id END,
+				CASE type WHEN 'transfer_in' THEN 2 ELSE 1 END,
+				account_id,
+				CASE type WHEN 'deposit' THEN amount WHEN 'transfer_in' THEN amount ELSE -amount END,
+				currency
+			FROM transactions
+			WHERE journal_entry_id IS NULL AND amount <> 0;
+
+		INSERT INTO postings(journal_entry_id, line, account_id, amount, currency)
+			SELECT CASE t.type WHEN 'transfer_in' THEN t.related_transaction_id ELSE t.id END,
+				CASE t.type WHEN 'transfer_out' THEN 3 WHEN 'transfer_in' THEN 4 ELSE 2 END,
+				s.id,
+				CASE t.type WHEN 'deposit' THEN -t.amount WHEN 'transfer_in' THEN -t.amount ELSE t.amount END,
+				t.currency
+			FROM transactions t
+			JOIN accounts s ON s.currency = t.currency AND s.system_account = CASE
+				WHEN t.type IN ('deposit', 'withdrawal') THEN 'cash'
+				ELSE 'fx'
+			END
+			WHERE t.journal_entry_id IS NULL AND t.amount <> 0
+			AND (t.type IN ('deposit', 'withdrawal') OR t.fx_rate IS NOT NULL);
+
+		UPDATE transactions SET journal_entry_id = CASE type
+				WHEN 'transfer_in' THEN related_transaction_id
+				ELSE id
+			END
+			WHERE journal_entry_id IS NULL;
+
+		SET CONSTRAINTS postings_balanced IMMEDIATE;
+		ALTER TABLE postings ENABLE TRIGGER postings_balance;
+
+		ALTER TABLE transactions ALTER COLUMN journal_entry_id SET NOT NULL;
+	`)
+	if err != nil {
+		return fmt.Errorf("Error posting journal entries for existing transactions: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"chariot-assessment/pkg/id"
+	"chariot-assessment/pkg/money"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// These benchmarks compare the varchar and bytea representations of IDs.
+// They need a running postgres configured through the same DB_* environment
+// variables as the service, and are skipped otherwise:
+//
+//	DB_HOST=localhost DB_USER=postgres DB_PASSWORD=password DB_NAME=chariot \
+//		go test -run=^$ -bench=InsertID -benchtime=100000x
+
+func BenchmarkInsertIDText(b *testing.B) {
+	benchmarkInsertID(b, id.StorageText)
+}
+
+func BenchmarkInsertIDBinary(b *testing.B) {
+	benchmarkInsertID(b, id.StorageBinary)
+}
+
+// benchmarkInsertID inserts b.N IDs into a table keyed by ID in the given
+// storage format, reporting the size of its primary key index per row.
+func benchmarkInsertID(b *testing.B, format id.StorageFormat) {
+	if os.Getenv("DB_HOST") == "" {
+		b.Skip("DB_HOST is not set")
+	}
+	db, err := sql.Open("postgres", dsnFromEnv())
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	prev := id.GetStorageFormat()
+	id.SetStorageFormat(format)
+	defer id.SetStorageFormat(prev)
+
+	table := "bench_ids_text"
+	if format == id.StorageBinary {
+		table = "bench_ids_binary"
+	}
+	_, err = db.Exec(fmt.Sprintf(`
+		DROP TABLE IF EXISTS %[1]s;
+		CREATE TABLE %[1]s(
+			id %[2]s PRIMARY KEY,
+			created_at timestamp DEFAULT current_timestamp
+		);
+	`, table, idColumnType()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %s`, table))
+
+	g, err := id.NewGenerator(id.WithOverflowPolicy(id.OverflowBorrow))
+	if err != nil {
+		b.Fatal(err)
+	}
+	insert := fmt.Sprintf(`INSERT INTO %s(id) VALUES ($1)`, table)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		next, err := g.New()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := db.Exec(insert, next); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+
+	var indexSize int64
+	err = db.QueryRow(`SELECT pg_relation_size($1::regclass)`, table+"_pkey").Scan(&indexSize)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportMetric(float64(indexSize)/float64(b.N), "index-B/row")
+}
+
+func TestIDArray(t *testing.T) {
+	prev := id.GetStorageFormat()
+	defer id.SetStorageFormat(prev)
+	for _, format := range []id.StorageFormat{id.StorageText, id.StorageBinary} {
+		id.SetStorageFormat(format)
+		// an unfiltered query relies on a NULL array
+		for _, ids := range [][]id.AccountID{nil, {}} {
+			if v := idArray(ids); v != nil {
+				t.Fatalf("format %d: idArray(%#v) = %#v, want nil", format, ids, v)
+			}
+		}
+		accountID, err := id.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := idArray([]id.AccountID{id.AccountID(accountID)}).Value()
+		if err != nil || v == nil {
+			t.Fatalf("format %d: got %v, %v, want a non-NULL array", format, v, err)
+		}
+	}
+}
+
+// TestIDCreatedAt checks chariot_id_created_at, which MigrateCreatedAt uses
+// to backfill created_at, against ID.OrderedTime for both ID versions. It
+// needs a running postgres like the benchmarks above.
+func TestIDCreatedAt(t *testing.T) {
+	if os.Getenv("DB_HOST") == "" {
+		t.Skip("DB_HOST is not set")
+	}
+	db, err := sql.Open("postgres", dsnFromEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(idFunctions); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, version := range []id.Version{id.V1, id.V2} {
+		g, err := id.NewGenerator(id.WithVersion(version), id.WithOverflowPolicy(id.OverflowBorrow))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids, err := g.NewBatch(1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, next := range ids {
+			var got time.Time
+			err := db.QueryRow(`SELECT chariot_id_created_at($1, $2)`, next.Bytes(), id.DefaultEpoch.Unix()).Scan(&got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := next.OrderedTime(); !got.Equal(want) {
+				t.Fatalf("%v: chariot_id_created_at = %v, want %v", next, got, want)
+			}
+		}
+	}
+}
+
+// testSchema points pgClient at a throwaway schema of the database
+// configured like the benchmarks above, and idGenerator at a new generator,
+// for the duration of a test. The schema is dropped afterwards.
+func testSchema(t *testing.T) *sql.DB {
+	t.Helper()
+	if os.Getenv("DB_HOST") == "" {
+		t.Skip("DB_HOST is not set")
+	}
+	admin, err := sql.Open("postgres", dsnFromEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := fmt.Sprintf("chariot_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		admin.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		admin.Close()
+	})
+
+	// every connection of db resolves unqualified names in the schema
+	db, err := sql.Open("postgres", dsnFromEnv()+" search_path="+schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generator, err := id.NewGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevClient, prevGenerator := pgClient, idGenerator
+	pgClient, idGenerator = db, generator
+	t.Cleanup(func() {
+		pgClient, idGenerator = prevClient, prevGenerator
+		db.Close()
+	})
+	return db
+}
+
+// migrate runs the given startup migrations, in order
+func migrate(t *testing.T, migrations ...func() error) {
+	t.Helper()
+	for _, m := range migrations {
+		if err := m(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestLedgerTriggers checks that the database projects postings onto
+// account balances and rejects unbalanced journal entries. It migrates a
+// throwaway schema as the service does on startup.
+func TestLedgerTriggers(t *testing.T) {
+	db := testSchema(t)
+	migrate(t, CreateSchema, MigrateIDStorage, MigrateCreatedAt, MigrateCurrency, MigrateLedger)
+
+	// begin starts a transaction holding a new user with a EUR account
+	begin := func() (*sql.Tx, id.AccountID) {
+		t.Helper()
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ensureSystemAccounts(tx, "EUR"); err != nil {
+			t.Fatal(err)
+		}
+		ids, err := idGenerator.NewBatch(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tx.Exec(`INSERT INTO users(id, name, created_at) VALUES ($1, 'ledger test', $2)`, ids[0], idCreatedAt(ids[0]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tx.Exec(`
+			INSERT INTO accounts(id, user_id, currency, created_at) VALUES ($1, $2, 'EUR', $3)
+		`, ids[1], ids[0], idCreatedAt(ids[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx, id.AccountID(ids[1])
+	}
+
+	tx, accountID := begin()
+	postings, err := cashPostings(tx, accountID, money.MustParse("12.5"), "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entryID, err := idGenerator.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := postEntry(tx, entryID, postings); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec(`SET CONSTRAINTS postings_balanced IMMEDIATE`); err != nil {
+		t.Fatalf("balanced entry was rejected: %v", err)
+	}
+	if balance, err := accountBalance(tx, accountID); err != nil || balance != money.MustParse("12.5") {
+		t.Fatalf("got balance %v, %v, want 12.5", balance, err)
+	}
+	if _, err := tx.Exec(`UPDATE postings SET amount = 1 WHERE journal_entry_id = $1`, entryID); err == nil {
+		t.Fatal("posting was updated")
+	}
+	tx.Rollback()
+
+	tx, accountID = begin()
+	defer tx.Rollback()
+	entryID, err = idGenerator.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tx.Exec(`INSERT INTO journal_entries(id, created_at) VALUES ($1, $2)`, entryID, idCreatedAt(entryID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO postings(journal_entry_id, line, account_id, amount, currency) VALUES ($1, 1, $2, 100, 'EUR')
+	`, entryID, accountID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec(`SET CONSTRAINTS postings_balanced IMMEDIATE`); err == nil {
+		t.Fatal("unbalanced entry was accepted")
+	}
+}
+
+// TestMigrateLedgerZeroAmount checks that transactions of zero, which were
+// accepted before the ledger existed, are backfilled into journal entries
+// without postings
+func TestMigrateLedgerZeroAmount(t *testing.T) {
+	db := testSchema(t)
+	migrate(t, CreateSchema, MigrateIDStorage, MigrateCreatedAt, MigrateCurrency)
+
+	ids, err := idGenerator.NewBatch(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, accountID, transactionID := ids[0], ids[1], ids[2]
+	_, err = db.Exec(`INSERT INTO users(id, name, created_at) VALUES ($1, 'ledger test', $2)`, userID, idCreatedAt(userID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+		INSERT INTO accounts(id, user_id, currency, created_at) VALUES ($1, $2, 'EUR', $3)
+	`, accountID, userID, idCreatedAt(accountID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+		INSERT INTO transactions(id, account_id, idempotency_key, amount, currency, ending_balance, type, created_at)
+		VALUES ($1, $2, 'zero', 0, 'EUR', 0, 'deposit', $3)
+	`, transactionID, accountID, idCreatedAt(transactionID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrate(t, MigrateLedger)
+
+	var entryID id.ID
+	err = db.QueryRow(`SELECT journal_entry_id FROM transactions WHERE id = $1`, transactionID).Scan(&entryID)
+	if err != nil || entryID != transactionID {
+		t.Fatalf("got journal entry %v, %v, want %v", entryID, err, transactionID)
+	}
+	var postings int
+	if err := db.QueryRow(`SELECT count(*) FROM postings WHERE journal_entry_id = $1`, entryID).Scan(&postings); err != nil {
+		t.Fatal(err)
+	}
+	if postings != 0 {
+		t.Fatalf("got %d postings for a zero deposit, want 0", postings)
+	}
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"chariot-assessment/pkg/fx"
 	"chariot-assessment/pkg/id"
+	"chariot-assessment/pkg/money"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -10,7 +12,6 @@ import (
 	"github.com/lib/pq"
 	"io"
 	"io/ioutil"
-	"math"
 	"net/http"
 	"strconv"
 	"time"
@@ -20,12 +21,32 @@ func health(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeError responds with status and a JSON body describing the error
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Error: msg,
+	})
+}
+
+// parseAccountID parses an account ID taken from the URL, accepting only
+// the canonical form and rejecting IDs this service could not have issued
+func parseAccountID(s string) (id.AccountID, error) {
+	parsed, err := idValidator.ParseKind(id.KindAccount, s)
+	return id.AccountID(parsed), err
+}
+
 type NewUser struct {
 	Name string `json:"name"`
 }
 
 type NewUserResponse struct {
-	UserId string `json:"userId"`
+	UserId id.UserID `json:"userId"`
 }
 
 func createUser(w http.ResponseWriter, r *http.Request) {
@@ -46,19 +67,19 @@ func createUser(w http.ResponseWriter, r *http.Request) {
 	err = json.Unmarshal(body, &user)
 	if err != nil {
 		fmt.Println("Could not unmarshal request body", err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
 		return
 	}
 
-	userId, err := id.New()
+	userId, err := idGenerator.New()
 	if err != nil {
 		fmt.Println("Could not generate ID:", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 	_, err = pgClient.Exec(`
-    INSERT INTO users(id, name) VALUES ($1, $2)
-    `, userId.String(), user.Name)
+    INSERT INTO users(id, name, created_at) VALUES ($1, $2, $3)
+    `, userId, user.Name, idCreatedAt(userId))
 	if err != nil {
 		fmt.Println("Error while inserting into postgres:", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -69,16 +90,18 @@ func createUser(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(NewUserResponse{
-		UserId: userId.String(),
+		UserId: id.UserID(userId),
 	})
 }
 
 type NewAccount struct {
-	UserId string `json:"userId"`
+	UserId   id.UserID      `json:"userId"`
+	Currency money.Currency `json:"currency"`
 }
 
 type NewAccountResponse struct {
-	AccountId string `json:"accountId"`
+	AccountId id.AccountID   `json:"accountId"`
+	Currency  money.Currency `json:"currency"`
 }
 
 func createAccount(w http.ResponseWriter, r *http.Request) {
@@ -102,61 +125,121 @@ func createAccount(w http.ResponseWriter, r *http.Request) {
 	err = json.Unmarshal(body, &accountReq)
 	if err != nil {
 		fmt.Println("Could not unmarshal request body", err)
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	if accountReq.UserId == (id.UserID{}) {
+		writeError(w, http.StatusBadRequest, "missing userId")
+		return
+	}
+	if accountReq.Currency == "" {
+		writeError(w, http.StatusBadRequest, "missing currency")
 		return
 	}
 
-	accountId, err := id.New()
+	tx, err := pgClient.Begin()
+	if err != nil {
+		fmt.Println("Could not begin transaction:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// the first account in a currency creates its system accounts
+	if err := ensureSystemAccounts(tx, accountReq.Currency); err != nil {
+		fmt.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	accountId, err := idGenerator.New()
 	if err != nil {
 		fmt.Println("Could not generate ID:", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	_, err = pgClient.Exec(`
-    INSERT INTO accounts(id, user_id) VALUES ($1, $2)
-    `, accountId.String(), accountReq.UserId)
+	_, err = tx.Exec(`
+    INSERT INTO accounts(id, user_id, currency, created_at) VALUES ($1, $2, $3, $4)
+    `, accountId, accountReq.UserId, accountReq.Currency, idCreatedAt(accountId))
 	if err != nil {
 		fmt.Println("Error while inserting into postgres:", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	if err := tx.Commit(); err != nil {
+		fmt.Println("Error while committing transaction:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(NewAccountResponse{
-		AccountId: accountId.String(),
+		AccountId: id.AccountID(accountId),
+		Currency:  accountReq.Currency,
 	})
 }
 
+// queryRower is implemented by *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// accountCurrency returns the currency of an account, or sql.ErrNoRows if
+// there is no such account. System accounts are not visible to the API.
+func accountCurrency(q queryRower, accountID id.AccountID) (money.Currency, error) {
+	var currency money.Currency
+	err := q.QueryRow(`
+		SELECT currency FROM accounts WHERE id = $1 AND system_account IS NULL
+	`, accountID).Scan(&currency)
+	return currency, err
+}
+
+// checkAccountAmount returns the currency of an account, appending an error
+// to fields if amount is not valid in it, unless fields already reports
+// amount. It writes an error response and returns false if the account does
+// not exist.
+func checkAccountAmount(w http.ResponseWriter, q queryRower, accountID id.AccountID, amount money.Amount, fields []FieldError) (money.Currency, []FieldError, bool) {
+	currency, err := accountCurrency(q, accountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, "account not found")
+		} else {
+			fmt.Println("Error while reading account:", err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return "", fields, false
+	}
+	if !hasField(fields, "amount") {
+		if err := currency.Check(amount); err != nil {
+			fields = append(fields, FieldError{Field: "amount", Message: err.Error()})
+		}
+	}
+	return currency, fields, true
+}
+
 type DepositWithdrawRequest struct {
-	Amount         float64 `json:"amount"`
-	IdempotencyKey string  `json:"idempotencyKey"`
+	Amount         money.Amount `json:"amount"`
+	IdempotencyKey string       `json:"idempotencyKey"`
+}
+
+// Validate implements requestValidator
+func (req DepositWithdrawRequest) Validate() []FieldError {
+	errs := validateAmount("amount", req.Amount)
+	return append(errs, validateIdempotencyKey(req.IdempotencyKey)...)
 }
 
 func deposit(w http.ResponseWriter, r *http.Request) {
 	var req DepositWithdrawRequest
 
-	vars := mux.Vars(r)
-	accountId := vars["account_id"]
-	if accountId == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
-	body, err := ioutil.ReadAll(r.Body)
+	accountId, err := parseAccountID(mux.Vars(r)["account_id"])
 	if err != nil {
-		fmt.Println("Could not read body", err)
-		w.WriteHeader(http.StatusBadRequest)
+		fmt.Println("Invalid account ID:", err)
+		writeError(w, http.StatusBadRequest, "invalid account_id: "+err.Error())
 		return
 	}
-	defer func() {
-		// Drain the request body
-		io.Copy(ioutil.Discard, r.Body)
-		r.Body.Close()
-	}()
 
-	err = json.Unmarshal(body, &req)
-	if err != nil {
-		fmt.Println("Could not unmarshal request body", err)
-		w.WriteHeader(http.StatusBadRequest)
+	fields, ok := decodeRequestFields(w, r, &req)
+	if !ok {
 		return
 	}
 
@@ -169,34 +252,47 @@ func deposit(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback()
 
-	// row is implicitly locked
-	var newBalance float64
-	amount := math.Abs(req.Amount)
-	err = tx.QueryRow(`
-		UPDATE accounts
-		SET balance = balance + $1
-		WHERE id = $2
-		RETURNING balance
-	`, amount, accountId).Scan(&newBalance)
-	if err != nil {
-		fmt.Println("Error while updating account balance:", err)
-		w.WriteHeader(http.StatusInternalServerError)
+	amount := req.Amount
+	currency, fields, ok := checkAccountAmount(w, tx, accountId, amount, fields)
+	if !ok {
+		return
+	}
+	if len(fields) > 0 {
+		writeValidationErrors(w, fields...)
 		return
 	}
 
 	// Generate transaction ID
-	transactionId, err := id.New()
+	transactionId, err := idGenerator.New()
 	if err != nil {
 		fmt.Println("Could not generate ID:", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	// Credit the account from cash; the account row is implicitly locked
+	// when the posting updates its balance
+	postings, err := cashPostings(tx, accountId, amount, currency)
+	if err == nil {
+		err = postEntry(tx, transactionId, postings)
+	}
+	if err != nil {
+		fmt.Println("Error while posting deposit:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	newBalance, err := accountBalance(tx, accountId)
+	if err != nil {
+		fmt.Println("Error while reading account balance:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	// Insert transaction record with ending_balance and idempotency_key
 	_, err = tx.Exec(`
-		INSERT INTO transactions(id, account_id, amount, type, ending_balance, idempotency_key)
-		VALUES ($1, $2, $3, 'deposit', $4, $5)
-	`, transactionId.String(), accountId, amount, newBalance, req.IdempotencyKey)
+		INSERT INTO transactions(id, account_id, amount, currency, type, ending_balance, idempotency_key, created_at, journal_entry_id)
+		VALUES ($1, $2, $3, $4, 'deposit', $5, $6, $7, $1)
+	`, transactionId, accountId, amount, currency, newBalance, req.IdempotencyKey, idCreatedAt(transactionId))
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
 			// Unique violation error code, idempotency key already exists
@@ -221,29 +317,15 @@ func deposit(w http.ResponseWriter, r *http.Request) {
 func withdraw(w http.ResponseWriter, r *http.Request) {
 	var req DepositWithdrawRequest
 
-	vars := mux.Vars(r)
-	accountId := vars["account_id"]
-	if accountId == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
-	body, err := ioutil.ReadAll(r.Body)
+	accountId, err := parseAccountID(mux.Vars(r)["account_id"])
 	if err != nil {
-		fmt.Println("Could not read body", err)
-		w.WriteHeader(http.StatusBadRequest)
+		fmt.Println("Invalid account ID:", err)
+		writeError(w, http.StatusBadRequest, "invalid account_id: "+err.Error())
 		return
 	}
-	defer func() {
-		// Drain the request body
-		io.Copy(ioutil.Discard, r.Body)
-		r.Body.Close()
-	}()
 
-	err = json.Unmarshal(body, &req)
-	if err != nil {
-		fmt.Println("Could not unmarshal request body", err)
-		w.WriteHeader(http.StatusBadRequest)
+	fields, ok := decodeRequestFields(w, r, &req)
+	if !ok {
 		return
 	}
 
@@ -256,39 +338,62 @@ func withdraw(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback()
 
-	// Check for sufficient balance & update it
-	var newBalance float64
-	amount := math.Abs(req.Amount)
+	amount := req.Amount
+	currency, fields, ok := checkAccountAmount(w, tx, accountId, amount, fields)
+	if !ok {
+		return
+	}
+	if len(fields) > 0 {
+		writeValidationErrors(w, fields...)
+		return
+	}
+
+	// Lock the account & check for sufficient balance
+	var balance money.Amount
 	err = tx.QueryRow(`
-		UPDATE accounts
-		SET balance = balance - $1
-		WHERE id = $2 AND balance >= $1
-		RETURNING balance
-	`, amount, accountId).Scan(&newBalance)
+		SELECT balance FROM accounts WHERE id = $1 FOR UPDATE
+	`, accountId).Scan(&balance)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			fmt.Println("Could not withdraw: Insufficient funds")
-			w.WriteHeader(http.StatusBadRequest)
-		} else {
-			fmt.Println("Error while updating account balance:", err)
-			w.WriteHeader(http.StatusInternalServerError)
-		}
+		fmt.Println("Error while reading account balance:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if balance.Cmp(amount) < 0 {
+		fmt.Println("Could not withdraw: Insufficient funds")
+		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
 	// Generate transaction ID
-	transactionId, err := id.New()
+	transactionId, err := idGenerator.New()
 	if err != nil {
 		fmt.Println("Could not generate ID:", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	// Debit the account to cash
+	postings, err := cashPostings(tx, accountId, amount.Neg(), currency)
+	if err == nil {
+		err = postEntry(tx, transactionId, postings)
+	}
+	if err != nil {
+		fmt.Println("Error while posting withdrawal:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	newBalance, err := accountBalance(tx, accountId)
+	if err != nil {
+		fmt.Println("Error while reading account balance:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	// Insert transaction record with ending_balance and idempotency_key
 	_, err = tx.Exec(`
-		INSERT INTO transactions(id, account_id, amount, type, ending_balance, idempotency_key)
-		VALUES ($1, $2, $3, 'withdrawal', $4, $5)
-	`, transactionId.String(), accountId, amount, newBalance, req.IdempotencyKey)
+		INSERT INTO transactions(id, account_id, amount, currency, type, ending_balance, idempotency_key, created_at, journal_entry_id)
+		VALUES ($1, $2, $3, $4, 'withdrawal', $5, $6, $7, $1)
+	`, transactionId, accountId, amount, currency, newBalance, req.IdempotencyKey, idCreatedAt(transactionId))
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
 			// Unique violation error code, idempotency key already exists
@@ -311,37 +416,123 @@ func withdraw(w http.ResponseWriter, r *http.Request) {
 }
 
 type TransferRequest struct {
-	Amount          float64 `json:"amount"`
-	IdempotencyKey  string  `json:"idempotencyKey"`
-	ExternalAccount string  `json:"externalAccount"`
+	Amount          money.Amount `json:"amount"`
+	IdempotencyKey  string       `json:"idempotencyKey"`
+	ExternalAccount id.AccountID `json:"externalAccount"`
+	// When the two accounts' currencies differ, the amount credited to
+	// ExternalAccount is either given in its currency by ConvertedAmount or
+	// converted at the rate of the quote QuoteId.
+	ConvertedAmount *money.Amount `json:"convertedAmount,omitempty"`
+	QuoteId         *id.QuoteID   `json:"quoteId,omitempty"`
 }
 
-func transfer(w http.ResponseWriter, r *http.Request) {
-	var req TransferRequest
+// Validate implements requestValidator
+func (req TransferRequest) Validate() []FieldError {
+	errs := validateAmount("amount", req.Amount)
+	errs = append(errs, validateIdempotencyKey(req.IdempotencyKey)...)
+	if req.ExternalAccount == (id.AccountID{}) {
+		errs = append(errs, FieldError{Field: "externalAccount", Message: "is required"})
+	}
+	if req.ConvertedAmount != nil {
+		errs = append(errs, validateAmount("convertedAmount", *req.ConvertedAmount)...)
+		if req.QuoteId != nil {
+			errs = append(errs, FieldError{Field: "quoteId", Message: "cannot be given with convertedAmount"})
+		}
+	}
+	return errs
+}
 
-	vars := mux.Vars(r)
-	accountId := vars["account_id"]
-	if accountId == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		return
+// convertTransfer returns the amount a transfer credits to the receiver, in
+// their currency, and the exchange rate applied if the currencies differ.
+// It appends to fields an error for each field that does not say how to
+// convert the amount, skipping those fields already reports. It writes an
+// error response and returns false if the quote cannot be read. Requests
+// have been validated, so at most one of quoteId and convertedAmount is set.
+func convertTransfer(w http.ResponseWriter, tx *sql.Tx, req TransferRequest, amount money.Amount, from, to money.Currency, fields []FieldError) (money.Amount, *money.Rate, []FieldError, bool) {
+	switch {
+	case req.QuoteId != nil:
+		if hasField(fields, "quoteId") {
+			return 0, nil, fields, true
+		}
+		var quote Quote
+		err := tx.QueryRow(`
+			SELECT id, from_currency, to_currency, rate, expires_at
+			FROM fx_quotes
+			WHERE id = $1
+		`, *req.QuoteId).Scan(&quote.ID, &quote.From, &quote.To, &quote.Rate, &quote.ExpiresAt)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				writeError(w, http.StatusNotFound, "quote not found")
+			} else {
+				fmt.Println("Error while reading quote:", err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return 0, nil, fields, false
+		}
+		if quote.From != from || quote.To != to {
+			fields = append(fields, FieldError{Field: "quoteId", Message: fmt.Sprintf("is for %s to %s, but the transfer is from %s to %s", quote.From, quote.To, from, to)})
+			return 0, nil, fields, true
+		}
+		if time.Now().After(quote.ExpiresAt) {
+			fields = append(fields, FieldError{Field: "quoteId", Message: "expired at " + quote.ExpiresAt.Format(time.RFC3339)})
+			return 0, nil, fields, true
+		}
+		if hasField(fields, "amount") {
+			return 0, nil, fields, true
+		}
+		converted, err := quote.Rate.Convert(amount, to)
+		if err != nil {
+			fields = append(fields, FieldError{Field: "amount", Message: err.Error()})
+			return 0, nil, fields, true
+		}
+		if converted.IsZero() {
+			fields = append(fields, FieldError{Field: "amount", Message: fmt.Sprintf("converts to 0 %s", to)})
+			return 0, nil, fields, true
+		}
+		return converted, &quote.Rate, fields, true
+
+	case hasField(fields, "convertedAmount"):
+		return 0, nil, fields, true
+
+	case from == to:
+		if req.ConvertedAmount != nil && *req.ConvertedAmount != amount && !hasField(fields, "amount") {
+			fields = append(fields, FieldError{Field: "convertedAmount", Message: "must equal amount for transfers within " + from.String()})
+		}
+		return amount, nil, fields, true
+
+	case req.ConvertedAmount == nil:
+		fields = append(fields, FieldError{Field: "convertedAmount", Message: fmt.Sprintf("is required for transfers from %s to %s unless quoteId is given", from, to)})
+		return 0, nil, fields, true
 	}
 
-	body, err := ioutil.ReadAll(r.Body)
+	converted := *req.ConvertedAmount
+	if err := to.Check(converted); err != nil {
+		fields = append(fields, FieldError{Field: "convertedAmount", Message: err.Error()})
+		return 0, nil, fields, true
+	}
+	if hasField(fields, "amount") {
+		return 0, nil, fields, true
+	}
+	rate, err := money.ImpliedRate(amount, converted)
 	if err != nil {
-		fmt.Println("Could not read body", err)
-		w.WriteHeader(http.StatusBadRequest)
-		return
+		fields = append(fields, FieldError{Field: "convertedAmount", Message: err.Error()})
+		return 0, nil, fields, true
 	}
-	defer func() {
-		// Drain the request body
-		io.Copy(ioutil.Discard, r.Body)
-		r.Body.Close()
-	}()
+	return converted, &rate, fields, true
+}
 
-	err = json.Unmarshal(body, &req)
+func transfer(w http.ResponseWriter, r *http.Request) {
+	var req TransferRequest
+
+	accountId, err := parseAccountID(mux.Vars(r)["account_id"])
 	if err != nil {
-		fmt.Println("Could not unmarshal request body", err)
-		w.WriteHeader(http.StatusBadRequest)
+		fmt.Println("Invalid account ID:", err)
+		writeError(w, http.StatusBadRequest, "invalid account_id: "+err.Error())
+		return
+	}
+
+	fields, ok := decodeRequestFields(w, r, &req)
+	if !ok {
 		return
 	}
 
@@ -365,59 +556,89 @@ func transfer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check for sufficient balance & update sender's balance
-	var senderNewBalance float64
-	amount := math.Abs(req.Amount)
-	err = tx.QueryRow(`
-		UPDATE accounts
-		SET balance = balance - $1
-		WHERE id = $2 AND balance >= $1
-		RETURNING balance
-	`, amount, accountId).Scan(&senderNewBalance)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			fmt.Println("Could not transfer: Insufficient funds")
-			w.WriteHeader(http.StatusBadRequest)
-		} else {
-			fmt.Println("Error while updating sender's account balance:", err)
-			w.WriteHeader(http.StatusInternalServerError)
+	amount := req.Amount
+	senderCurrency, fields, ok := checkAccountAmount(w, tx, accountId, amount, fields)
+	if !ok {
+		return
+	}
+	var (
+		receiverCurrency money.Currency
+		convertedAmount  money.Amount
+		fxRate           *money.Rate
+	)
+	// without a receiver there is no conversion to check
+	if !hasField(fields, "externalAccount") {
+		receiverCurrency, err = accountCurrency(tx, req.ExternalAccount)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				writeError(w, http.StatusNotFound, "externalAccount not found")
+			} else {
+				fmt.Println("Error while reading receiver's account:", err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
 		}
+		convertedAmount, fxRate, fields, ok = convertTransfer(w, tx, req, amount, senderCurrency, receiverCurrency, fields)
+		if !ok {
+			return
+		}
+	}
+	if len(fields) > 0 {
+		writeValidationErrors(w, fields...)
 		return
 	}
 
-	// Update receiver's balance
-	var receiverNewBalance float64
-	err = tx.QueryRow(`
-		UPDATE accounts
-		SET balance = balance + $1
-		WHERE id = $2
-		RETURNING balance
-	`, amount, req.ExternalAccount).Scan(&receiverNewBalance)
+	// Check for sufficient balance
+	senderBalance, err := accountBalance(tx, accountId)
+	if err != nil {
+		fmt.Println("Error while reading sender's account balance:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if senderBalance.Cmp(amount) < 0 {
+		fmt.Println("Could not transfer: Insufficient funds")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Generate both transaction IDs at once, so the sender's always sorts
+	// immediately before the receiver's
+	transactionIds, err := idGenerator.NewBatch(2)
 	if err != nil {
-		fmt.Println("Error while updating receiver's account balance:", err)
+		fmt.Println("Could not generate transaction IDs:", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	senderTransactionId, receiverTransactionId := transactionIds[0], transactionIds[1]
 
-	// Generate transaction IDs
-	senderTransactionId, err := id.New()
+	// Debit the sender & credit the receiver in a single journal entry
+	postings, err := transferPostings(tx, accountId, req.ExternalAccount, amount, convertedAmount, senderCurrency, receiverCurrency)
+	if err == nil {
+		err = postEntry(tx, senderTransactionId, postings)
+	}
+	if err != nil {
+		fmt.Println("Error while posting transfer:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	senderNewBalance, err := accountBalance(tx, accountId)
 	if err != nil {
-		fmt.Println("Could not generate sender transaction ID:", err)
+		fmt.Println("Error while reading sender's account balance:", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	receiverTransactionId, err := id.New()
+	receiverNewBalance, err := accountBalance(tx, req.ExternalAccount)
 	if err != nil {
-		fmt.Println("Could not generate receiver transaction ID:", err)
+		fmt.Println("Error while reading receiver's account balance:", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	// Insert sender's transaction record
 	_, err = tx.Exec(`
-		INSERT INTO transactions(id, account_id, amount, type, ending_balance, idempotency_key)
-		VALUES ($1, $2, $3, 'transfer_out', $4, $5)
-	`, senderTransactionId.String(), accountId, amount, senderNewBalance, req.IdempotencyKey)
+		INSERT INTO transactions(id, account_id, amount, currency, type, ending_balance, idempotency_key, created_at, fx_rate, fx_quote_id, journal_entry_id)
+		VALUES ($1, $2, $3, $4, 'transfer_out', $5, $6, $7, $8, $9, $1)
+	`, senderTransactionId, accountId, amount, senderCurrency, senderNewBalance, req.IdempotencyKey, idCreatedAt(senderTransactionId), fxRate, req.QuoteId)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
 			// Unique violation error code, idempotency key already exists
@@ -431,9 +652,9 @@ func transfer(w http.ResponseWriter, r *http.Request) {
 
 	// Insert receiver's transaction record
 	_, err = tx.Exec(`
-		INSERT INTO transactions(id, account_id, amount, type, ending_balance, idempotency_key)
-		VALUES ($1, $2, $3, 'transfer_in', $4, $5)
-	`, receiverTransactionId.String(), req.ExternalAccount, amount, receiverNewBalance, req.IdempotencyKey)
+		INSERT INTO transactions(id, account_id, amount, currency, type, ending_balance, idempotency_key, created_at, fx_rate, fx_quote_id, journal_entry_id)
+		VALUES ($1, $2, $3, $4, 'transfer_in', $5, $6, $7, $8, $9, $10)
+	`, receiverTransactionId, req.ExternalAccount, convertedAmount, receiverCurrency, receiverNewBalance, req.IdempotencyKey, idCreatedAt(receiverTransactionId), fxRate, req.QuoteId, senderTransactionId)
 	if err != nil {
 		fmt.Println("Error while inserting receiver's transaction:", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -445,7 +666,7 @@ func transfer(w http.ResponseWriter, r *http.Request) {
 		UPDATE transactions
 		SET related_transaction_id = $1
 		WHERE id = $2
-	`, receiverTransactionId.String(), senderTransactionId.String())
+	`, receiverTransactionId, senderTransactionId)
 	if err != nil {
 		fmt.Println("Error while updating sender's transaction with related_transaction_id:", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -457,7 +678,7 @@ func transfer(w http.ResponseWriter, r *http.Request) {
 		UPDATE transactions
 		SET related_transaction_id = $1
 		WHERE id = $2
-	`, senderTransactionId.String(), receiverTransactionId.String())
+	`, senderTransactionId, receiverTransactionId)
 	if err != nil {
 		fmt.Println("Error while updating receiver's transaction with related_transaction_id:", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -475,24 +696,47 @@ func transfer(w http.ResponseWriter, r *http.Request) {
 }
 
 type Transaction struct {
-	ID                   string    `json:"id"`
-	AccountID            string    `json:"accountId"`
-	ExternalAccount      string    `json:"externalAccount,omitempty"`
-	Amount               float64   `json:"amount"`
-	Type                 string    `json:"type"`
-	EndingBalance        float64   `json:"endingBalance"`
-	RelatedTransactionID string    `json:"relatedTransactionId,omitempty"`
-	CreatedAt            time.Time `json:"createdAt"`
+	ID                   id.TransactionID  `json:"id"`
+	AccountID            id.AccountID      `json:"accountId"`
+	ExternalAccount      *id.AccountID     `json:"externalAccount,omitempty"`
+	Amount               money.Amount      `json:"amount"`
+	Currency             money.Currency    `json:"currency"`
+	Type                 string            `json:"type"`
+	EndingBalance        money.Amount      `json:"endingBalance"`
+	RelatedTransactionID *id.TransactionID `json:"relatedTransactionId,omitempty"`
+	CreatedAt            time.Time         `json:"createdAt"`
+	// Rate is the exchange rate applied to both legs of a transfer between
+	// currencies, and QuoteID the quote it came from, if any
+	Rate    *money.Rate `json:"rate,omitempty"`
+	QuoteID *id.QuoteID `json:"quoteId,omitempty"`
 }
 
 type ListTransactionsResponse struct {
-	Transactions []Transaction `json:"transactions"`
-	NextCursor   string        `json:"nextCursor,omitempty"`
+	Transactions []Transaction     `json:"transactions"`
+	NextCursor   *id.TransactionID `json:"nextCursor,omitempty"`
 }
 
 func listTransactions(w http.ResponseWriter, r *http.Request) {
-	accountIDs := r.URL.Query()["accountId"]
-	cursor := r.URL.Query().Get("cursor")
+	var accountIDs []id.AccountID
+	for _, s := range r.URL.Query()["accountId"] {
+		accountID, err := parseAccountID(s)
+		if err != nil {
+			fmt.Println("Invalid account ID:", err)
+			writeError(w, http.StatusBadRequest, "invalid accountId: "+err.Error())
+			return
+		}
+		accountIDs = append(accountIDs, accountID)
+	}
+	var cursor *id.TransactionID
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		parsed, err := id.ParseTransactionID(s)
+		if err != nil {
+			fmt.Println("Invalid cursor:", err)
+			writeError(w, http.StatusBadRequest, "invalid cursor: "+err.Error())
+			return
+		}
+		cursor = &parsed
+	}
 	limit := 10 // default
 
 	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
@@ -507,14 +751,14 @@ func listTransactions(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	rows, err := pgClient.Query(`
-		SELECT id, account_id, external_account, amount, type, ending_balance,
-			related_transaction_id, created_at
+	rows, err := pgClient.Query(fmt.Sprintf(`
+		SELECT id, account_id, external_account, amount, currency, type, ending_balance,
+			related_transaction_id, created_at, fx_rate, fx_quote_id
 		FROM transactions
-		WHERE ($1::text[] IS NULL OR account_id = ANY($1))
-		AND ($2 = '' OR id > $2)
+		WHERE ($1::%[1]s[] IS NULL OR account_id = ANY($1))
+		AND ($2::%[1]s IS NULL OR id > $2)
 		ORDER BY id
-		LIMIT $3`, pq.Array(accountIDs), cursor, limit+1)
+		LIMIT $3`, idColumnType()), idArray(accountIDs), cursor, limit+1)
 	if err != nil {
 		fmt.Println("Error querying transactions:", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -525,22 +769,14 @@ func listTransactions(w http.ResponseWriter, r *http.Request) {
 	transactions := []Transaction{}
 	for rows.Next() {
 		var t Transaction
-		var externalAccount, relatedTransactionID sql.NullString
-		err := rows.Scan(&t.ID, &t.AccountID, &externalAccount, &t.Amount,
-			&t.Type, &t.EndingBalance, &relatedTransactionID, &t.CreatedAt)
+		err := rows.Scan(&t.ID, &t.AccountID, &t.ExternalAccount, &t.Amount,
+			&t.Currency, &t.Type, &t.EndingBalance, &t.RelatedTransactionID, &t.CreatedAt,
+			&t.Rate, &t.QuoteID)
 		if err != nil {
 			fmt.Println("Error scanning transaction row:", err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
-
-		// if external_account || related_transaction_id is non-null
-		if externalAccount.Valid {
-			t.ExternalAccount = externalAccount.String
-		}
-		if relatedTransactionID.Valid {
-			t.RelatedTransactionID = relatedTransactionID.String
-		}
 		transactions = append(transactions, t)
 	}
 
@@ -551,26 +787,29 @@ func listTransactions(w http.ResponseWriter, r *http.Request) {
 	// if there are more transactions than requested, set the next cursor
 	if len(transactions) > limit {
 		response.Transactions = transactions[:limit]
-		response.NextCursor = transactions[limit-1].ID
+		response.NextCursor = &transactions[limit-1].ID
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-func getBalance(accountID string, atTime time.Time) (float64, error) {
-	var balance float64
+func getBalance(accountID id.AccountID, atTime time.Time) (money.Amount, error) {
+	var balance money.Amount
 	if pgClient == nil {
 		return balance, errors.New("postgres client has not been initialized")
 	}
 
+	// created_at holds UTC without a zone, which postgres would otherwise
+	// compare atTime's local wall clock against. Ties between V2 IDs in the
+	// same microsecond are broken by ID, which created_at never contradicts.
 	err := pgClient.QueryRow(`
 		SELECT ending_balance
 		FROM transactions
 		WHERE account_id = $1
 		AND created_at <= $2
-		ORDER BY created_at DESC
-		LIMIT 1`, accountID, atTime).Scan(&balance)
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1`, accountID, atTime.UTC()).Scan(&balance)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return balance, nil // Return 0 balance if no transactions found
@@ -582,13 +821,19 @@ func getBalance(accountID string, atTime time.Time) (float64, error) {
 }
 
 type AccountBalanceResponse struct {
-	AccountID string    `json:"accountId"`
-	Balance   float64   `json:"balance"`
-	Timestamp time.Time `json:"timestamp,string"`
+	AccountID id.AccountID   `json:"accountId"`
+	Balance   money.Amount   `json:"balance"`
+	Currency  money.Currency `json:"currency"`
+	Timestamp time.Time      `json:"timestamp,string"`
 }
 
 func getAccountBalance(w http.ResponseWriter, r *http.Request) {
-	accountID := mux.Vars(r)["account_id"]
+	accountID, err := parseAccountID(mux.Vars(r)["account_id"])
+	if err != nil {
+		fmt.Println("Invalid account ID:", err)
+		writeError(w, http.StatusBadRequest, "invalid account_id: "+err.Error())
+		return
+	}
 	timestamp := r.URL.Query().Get("timestamp")
 	ts := time.Now()
 	if timestamp != "" {
@@ -602,19 +847,16 @@ func getAccountBalance(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if the account exists
-	var exists bool
-	err := pgClient.QueryRow(`
-		SELECT EXISTS(SELECT 1 FROM accounts WHERE id = $1)
-	`, accountID).Scan(&exists)
+	currency, err := accountCurrency(pgClient, accountID)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
 		fmt.Println("Error checking account existence:", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	if !exists {
-		w.WriteHeader(http.StatusNotFound)
-		return
-	}
 
 	balance, err := getBalance(accountID, ts)
 	if err != nil {
@@ -627,6 +869,96 @@ func getAccountBalance(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(AccountBalanceResponse{
 		AccountID: accountID,
 		Balance:   balance,
+		Currency:  currency,
 		Timestamp: ts,
 	})
 }
+
+type QuoteRequest struct {
+	From money.Currency `json:"from"`
+	To   money.Currency `json:"to"`
+}
+
+// Quote locks the rate from one currency to another until ExpiresAt, for
+// transfers that give its ID as quoteId
+type Quote struct {
+	ID        id.QuoteID     `json:"quoteId"`
+	From      money.Currency `json:"from"`
+	To        money.Currency `json:"to"`
+	Rate      money.Rate     `json:"rate"`
+	ExpiresAt time.Time      `json:"expiresAt"`
+}
+
+func createQuote(w http.ResponseWriter, r *http.Request) {
+	if fxProvider == nil {
+		writeError(w, http.StatusServiceUnavailable, "no exchange rate provider is configured")
+		return
+	}
+
+	var req QuoteRequest
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		fmt.Println("Could not read body", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer func() {
+		// Drain the request body
+		io.Copy(ioutil.Discard, r.Body)
+		r.Body.Close()
+	}()
+
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		fmt.Println("Could not unmarshal request body", err)
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if req.From == "" || req.To == "" {
+		writeError(w, http.StatusBadRequest, "missing from or to currency")
+		return
+	}
+	if req.From == req.To {
+		writeError(w, http.StatusBadRequest, "from and to currencies must differ")
+		return
+	}
+
+	rate, err := fxProvider.Rate(r.Context(), req.From, req.To)
+	if err != nil {
+		if errors.Is(err, fx.ErrNoRate) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		fmt.Println("Error getting exchange rate:", err)
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	quoteId, err := idGenerator.New()
+	if err != nil {
+		fmt.Println("Could not generate ID:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	createdAt := idCreatedAt(quoteId)
+	quote := Quote{
+		ID:        id.QuoteID(quoteId),
+		From:      req.From,
+		To:        req.To,
+		Rate:      rate,
+		ExpiresAt: createdAt.Add(fxQuoteTTL),
+	}
+	_, err = pgClient.Exec(`
+		INSERT INTO fx_quotes(id, from_currency, to_currency, rate, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, quote.ID, quote.From, quote.To, quote.Rate, createdAt, quote.ExpiresAt)
+	if err != nil {
+		fmt.Println("Error while inserting quote:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(quote)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"chariot-assessment/pkg/id"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// withValidator sets idValidator for the duration of a test
+func withValidator(t *testing.T) {
+	t.Helper()
+	prev := idValidator
+	idValidator = &id.Validator{Epoch: id.DefaultEpoch, FutureTolerance: idFutureTolerance}
+	t.Cleanup(func() { idValidator = prev })
+}
+
+func TestParseAccountIDCheckSymbol(t *testing.T) {
+	withValidator(t)
+	for _, version := range []id.Version{id.V1, id.V2} {
+		g, err := id.NewGenerator(id.WithVersion(version))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := g.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range []string{
+			id.KindAccount.Prefix() + want.String(),
+			id.KindAccount.Prefix() + id.StdEncoding.WithCheck().EncodeToString(want),
+		} {
+			got, err := parseAccountID(s)
+			if err != nil || id.ID(got) != want {
+				t.Fatalf("parseAccountID(%q) = %v, %v, want %v", s, got, err, want)
+			}
+		}
+	}
+}
+
+func TestAccountPathTypo(t *testing.T) {
+	withValidator(t)
+	r := mux.NewRouter()
+	r.HandleFunc("/accounts/{account_id}/balance", getAccountBalance)
+	r.HandleFunc("/accounts/{account_id}/deposit", deposit)
+
+	account, err := id.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := id.StdEncoding.WithCheck().EncodeToString(account)
+	// swap two adjacent characters of the counter
+	typo := s[:8] + s[9:10] + s[8:9] + s[10:]
+	if typo == s {
+		typo = s[:9] + s[10:11] + s[9:10] + s[11:]
+	}
+	if typo == s {
+		t.Skip("generated ID has no distinct adjacent characters to swap")
+	}
+
+	for _, path := range []string{"/accounts/acct_" + typo + "/balance", "/accounts/acct_" + typo + "/deposit"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, strings.NewReader(`{}`)))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: got status %d, want 400", path, w.Code)
+		}
+		var resp ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Could not decode response %s: %v", w.Body, err)
+		}
+		if !strings.Contains(resp.Error, id.ErrCheckSymbol.Error()) {
+			t.Fatalf("%s: got error %q, want a check symbol mismatch", path, resp.Error)
+		}
+	}
+	if _, err := parseAccountID("acct_" + typo); !errors.Is(err, id.ErrCheckSymbol) {
+		t.Fatalf("parseAccountID(%q): got %v, want %v", typo, err, id.ErrCheckSymbol)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"chariot-assessment/pkg/id"
+	"chariot-assessment/pkg/money"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+)
+
+// Deposits, withdrawals and transfers are recorded in a double-entry
+// journal. Each is a journal entry whose postings credit (positive amounts)
+// or debit (negative amounts) accounts, summing to zero in every currency,
+// so money is only ever moved between accounts. Money entering or leaving
+// the service is posted to system accounts, which belong to no user and
+// are never exposed by the API. accounts.balance is a projection of the
+// postings, maintained by the database as they are inserted (see
+// ledgerTriggers).
+
+const (
+	// systemCash is the system account that deposits are debited from and
+	// withdrawals credited to, standing for cash held outside the service
+	systemCash = "cash"
+	// systemFX is the system account that cross-currency transfers exchange
+	// through: it is credited the amount sent and debited the amount
+	// received, in the respective currencies
+	systemFX = "fx"
+)
+
+// errUnbalanced is returned for journal entries whose postings do not sum
+// to zero in every currency
+var errUnbalanced = errors.New("journal entry does not balance")
+
+// posting credits Amount to an account, or debits it if Amount is negative
+type posting struct {
+	AccountID id.AccountID
+	Amount    money.Amount
+	Currency  money.Currency
+}
+
+// checkBalanced returns an error wrapping errUnbalanced unless every posting
+// is non-zero and the postings sum to zero in every currency
+func checkBalanced(postings []posting) error {
+	sums := map[money.Currency]money.Amount{}
+	for _, p := range postings {
+		if p.Amount.IsZero() {
+			return fmt.Errorf("%w: zero posting to %v", errUnbalanced, p.AccountID)
+		}
+		// amounts are within ±10^15, so the sum cannot overflow
+		sums[p.Currency] += p.Amount
+	}
+	for currency, sum := range sums {
+		if !sum.IsZero() {
+			return fmt.Errorf("%w: %s postings sum to %v", errUnbalanced, currency, sum)
+		}
+	}
+	return nil
+}
+
+// ensureSystemAccounts creates the system accounts for currency if they do
+// not exist yet. Once they do, it only reads them.
+func ensureSystemAccounts(tx *sql.Tx, currency money.Currency) error {
+	purposes := []string{systemCash, systemFX}
+	var existing int
+	err := tx.QueryRow(`
+		SELECT count(*) FROM accounts WHERE system_account = ANY($1) AND currency = $2
+	`, pq.Array(purposes), currency).Scan(&existing)
+	if err != nil {
+		return fmt.Errorf("Error reading %s system accounts: %w", currency, err)
+	}
+	if existing == len(purposes) {
+		return nil
+	}
+	for _, purpose := range purposes {
+		accountId, err := idGenerator.New()
+		if err != nil {
+			return fmt.Errorf("Could not generate ID: %w", err)
+		}
+		_, err = tx.Exec(`
+			INSERT INTO accounts(id, system_account, currency, created_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (system_account, currency) DO NOTHING
+		`, accountId, purpose, currency, idCreatedAt(accountId))
+		if err != nil {
+			return fmt.Errorf("Error creating %s %s account: %w", currency, purpose, err)
+		}
+	}
+	return nil
+}
+
+// systemAccount returns the ID of the system account for purpose in
+// currency
+func systemAccount(q queryRower, purpose string, currency money.Currency) (id.AccountID, error) {
+	var accountId id.AccountID
+	err := q.QueryRow(`
+		SELECT id FROM accounts WHERE system_account = $1 AND currency = $2
+	`, purpose, currency).Scan(&accountId)
+	if err != nil {
+		return accountId, fmt.Errorf("Error reading %s %s account: %w", currency, purpose, err)
+	}
+	return accountId, nil
+}
+
+// cashPostings returns the postings of a deposit, for a positive amount, or
+// a withdrawal, for a negative one
+func cashPostings(q queryRower, accountId id.AccountID, amount money.Amount, currency money.Currency) ([]posting, error) {
+	cash, err := systemAccount(q, systemCash, currency)
+	if err != nil {
+		return nil, err
+	}
+	return []posting{
+		{AccountID: accountId, Amount: amount, Currency: currency},
+		{AccountID: cash, Amount: amount.Neg(), Currency: currency},
+	}, nil
+}
+
+// transferPostings returns the postings of a transfer of amount from one
+// account that credits converted to another. Transfers between currencies
+// pass through the FX account of each.
+func transferPostings(q queryRower, from, to id.AccountID, amount, converted money.Amount, fromCurrency, toCurrency money.Currency) ([]posting, error) {
+	postings := []posting{
+		{AccountID: from, Amount: amount.Neg(), Currency: fromCurrency},
+		{AccountID: to, Amount: converted, Currency: toCurrency},
+	}
+	if fromCurrency == toCurrency {
+		return postings, nil
+	}
+	fxFrom, err := systemAccount(q, systemFX, fromCurrency)
+	if err != nil {
+		return nil, err
+	}
+	fxTo, err := systemAccount(q, systemFX, toCurrency)
+	if err != nil {
+		return nil, err
+	}
+	return append(postings,
+		posting{AccountID: fxFrom, Amount: amount, Currency: fromCurrency},
+		posting{AccountID: fxTo, Amount: converted.Neg(), Currency: toCurrency},
+	), nil
+}
+
+// postEntry records a journal entry with the given postings, numbered from
+// 1 in order. Entries share the ID of the transaction they record, or of
+// the sender's transaction for transfers.
+func postEntry(tx *sql.Tx, entryId id.ID, postings []posting) error {
+	if err := checkBalanced(postings); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`
+		INSERT INTO journal_entries(id, created_at) VALUES ($1, $2)
+	`, entryId, idCreatedAt(entryId))
+	if err != nil {
+		return fmt.Errorf("Error inserting journal entry: %w", err)
+	}
+	for i, p := range postings {
+		_, err := tx.Exec(`
+			INSERT INTO postings(journal_entry_id, line, account_id, amount, currency)
+			VALUES ($1, $2, $3, $4, $5)
+		`, entryId, i+1, p.AccountID, p.Amount, p.Currency)
+		if err != nil {
+			return fmt.Errorf("Error inserting posting: %w", err)
+		}
+	}
+	return nil
+}
+
+// accountBalance returns the current balance of an account
+func accountBalance(q queryRower, accountId id.AccountID) (money.Amount, error) {
+	var balance money.Amount
+	err := q.QueryRow(`
+		SELECT balance FROM accounts WHERE id = $1
+	`, accountId).Scan(&balance)
+	return balance, err
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"chariot-assessment/pkg/id"
+	"chariot-assessment/pkg/money"
+	"errors"
+	"testing"
+)
+
+func TestCheckBalanced(t *testing.T) {
+	ids, err := id.NewBatch(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, bob, fxUSD, fxEUR := id.AccountID(ids[0]), id.AccountID(ids[1]), id.AccountID(ids[2]), id.AccountID(ids[3])
+
+	balanced := [][]posting{
+		{
+			{AccountID: alice, Amount: money.MustParse("-10"), Currency: "USD"},
+			{AccountID: bob, Amount: money.MustParse("10"), Currency: "USD"},
+		},
+		{
+			{AccountID: alice, Amount: money.MustParse("-10"), Currency: "USD"},
+			{AccountID: bob, Amount: money.MustParse("9.2"), Currency: "EUR"},
+			{AccountID: fxUSD, Amount: money.MustParse("10"), Currency: "USD"},
+			{AccountID: fxEUR, Amount: money.MustParse("-9.2"), Currency: "EUR"},
+		},
+		{
+			{AccountID: alice, Amount: money.MaxAmount, Currency: "USD"},
+			{AccountID: alice, Amount: money.MaxAmount, Currency: "USD"},
+			{AccountID: bob, Amount: money.MaxAmount.Neg(), Currency: "USD"},
+			{AccountID: bob, Amount: money.MaxAmount.Neg(), Currency: "USD"},
+		},
+	}
+	for _, postings := range balanced {
+		if err := checkBalanced(postings); err != nil {
+			t.Fatalf("checkBalanced(%v): got error %v", postings, err)
+		}
+	}
+
+	unbalanced := [][]posting{
+		{
+			{AccountID: alice, Amount: money.MustParse("10"), Currency: "USD"},
+		},
+		{
+			{AccountID: alice, Amount: money.MustParse("-10"), Currency: "USD"},
+			{AccountID: bob, Amount: money.MustParse("10.0001"), Currency: "USD"},
+		},
+		{
+			// sums to zero overall, but not in each currency
+			{AccountID: alice, Amount: money.MustParse("-10"), Currency: "USD"},
+			{AccountID: bob, Amount: money.MustParse("10"), Currency: "EUR"},
+		},
+		{
+			{AccountID: alice, Amount: 0, Currency: "USD"},
+			{AccountID: bob, Amount: 0, Currency: "USD"},
+		},
+	}
+	for _, postings := range unbalanced {
+		if err := checkBalanced(postings); !errors.Is(err, errUnbalanced) {
+			t.Fatalf("checkBalanced(%v): got error %v, want errUnbalanced", postings, err)
+		}
+	}
+}
@@ -1,30 +1,52 @@
 package main
 
 import (
+	"chariot-assessment/pkg/fx"
+	"chariot-assessment/pkg/id"
 	"database/sql"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
 )
 
 var (
-	pgClient *sql.DB
+	pgClient    *sql.DB
+	idGenerator *id.Generator
+	idValidator *id.Validator
+	fxProvider  fx.Provider
+	fxQuoteTTL  = defaultFXQuoteTTL
 )
 
-func main() {
-	var err error
-	// Setup postgres client
+// idFutureTolerance is how far ahead of this replica's clock the IDs it is
+// given may be, since they may have been minted by a replica whose clock
+// runs ahead, or in a second borrowed under load.
+const idFutureTolerance = 5 * time.Second
+
+// defaultFXQuoteTTL is how long an FX quote's rate can be used for, unless
+// FX_QUOTE_TTL is set
+const defaultFXQuoteTTL = 30 * time.Second
+
+// dsnFromEnv builds the postgres connection string from DB_* environment
+// variables
+func dsnFromEnv() string {
 	dbName := os.Getenv("DB_NAME")
 	dbHost := os.Getenv("DB_HOST")
 	dbUser := os.Getenv("DB_USER")
 	dbPassword := os.Getenv("DB_PASSWORD")
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
 		dbHost, 5432, dbUser, dbPassword, dbName,
 	)
-	pgClient, err = sql.Open("postgres", dsn)
+}
+
+func main() {
+	var err error
+	// Setup postgres client
+	pgClient, err = sql.Open("postgres", dsnFromEnv())
 	if err != nil {
 		panic(err)
 	}
@@ -34,11 +56,99 @@ func main() {
 		panic(err)
 	}
 
+	// Setup ID generator. Each replica behind the load balancer must be
+	// given a distinct NODE_ID so their IDs can never collide.
+	var opts []id.Option
+	if nodeID := os.Getenv("NODE_ID"); nodeID != "" {
+		node, err := strconv.ParseUint(nodeID, 10, 16)
+		if err != nil {
+			panic(fmt.Errorf("invalid NODE_ID: %w", err))
+		}
+		opts = append(opts, id.WithNode(uint16(node)))
+	}
+	// Databases holding IDs minted before the epoch was corrected must set
+	// ID_EPOCH=legacy so the times decoded from their IDs stay exact.
+	// MigrateCreatedAt refuses to start otherwise.
+	switch epoch := os.Getenv("ID_EPOCH"); epoch {
+	case "", "default":
+	case "legacy":
+		opts = append(opts, id.WithEpoch(id.LegacyEpoch))
+	default:
+		t, err := time.Parse(time.RFC3339, epoch)
+		if err != nil {
+			panic(fmt.Errorf("invalid ID_EPOCH: %w", err))
+		}
+		opts = append(opts, id.WithEpoch(t))
+	}
+	idGenerator, err = id.NewGenerator(opts...)
+	if err != nil {
+		panic(err)
+	}
+	idValidator = &id.Validator{
+		Epoch:           idGenerator.Epoch(),
+		FutureTolerance: idFutureTolerance,
+	}
+
+	// IDs are stored as varchar by default. ID_STORAGE=binary stores them as
+	// bytea, migrating existing varchar columns on startup.
+	switch storage := os.Getenv("ID_STORAGE"); storage {
+	case "", "text":
+		id.SetStorageFormat(id.StorageText)
+	case "binary":
+		id.SetStorageFormat(id.StorageBinary)
+	default:
+		panic(fmt.Errorf("invalid ID_STORAGE %q: must be text or binary", storage))
+	}
+
+	// Downstream systems that only handle UUIDs can send IDs back in the
+	// UUIDv7 form returned by id.ID.UUID when ID_ACCEPT_UUID=true.
+	if accept := os.Getenv("ID_ACCEPT_UUID"); accept != "" {
+		enabled, err := strconv.ParseBool(accept)
+		if err != nil {
+			panic(fmt.Errorf("invalid ID_ACCEPT_UUID: %w", err))
+		}
+		id.SetUUIDText(enabled)
+	}
+
+	// Cross-currency transfers can use quotes from a rate provider.
+	// FX_RATES_FILE configures fixed rates for local testing (see
+	// fx_rates.json); without a provider only convertedAmount is accepted.
+	if path := os.Getenv("FX_RATES_FILE"); path != "" {
+		rates, err := fx.LoadFile(path)
+		if err != nil {
+			panic(err)
+		}
+		fxProvider = rates
+	}
+	if ttl := os.Getenv("FX_QUOTE_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d <= 0 {
+			panic(fmt.Errorf("invalid FX_QUOTE_TTL %q: must be a positive duration", ttl))
+		}
+		fxQuoteTTL = d
+	}
+
 	// Setup database
 	err = CreateSchema()
 	if err != nil {
 		panic(err)
 	}
+	err = MigrateIDStorage()
+	if err != nil {
+		panic(err)
+	}
+	err = MigrateCreatedAt()
+	if err != nil {
+		panic(err)
+	}
+	err = MigrateCurrency()
+	if err != nil {
+		panic(err)
+	}
+	err = MigrateLedger()
+	if err != nil {
+		panic(err)
+	}
 
 	r := mux.NewRouter()
 	r.HandleFunc("/health", health)
@@ -63,6 +173,9 @@ func main() {
 	r.HandleFunc("/transactions", listTransactions).
 		Methods("GET")
 
+	r.HandleFunc("/fx/quotes", createQuote).
+		Methods("POST")
+
 	fmt.Println("Service ready.")
 
 	http.ListenAndServe(":8080", r)
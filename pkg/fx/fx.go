@@ -0,0 +1,91 @@
+package fx
+
+import (
+	"chariot-assessment/pkg/money"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrNoRate is returned by Providers that cannot price a currency pair
+var ErrNoRate = errors.New("fx: no rate for currency pair")
+
+// A Provider supplies exchange rates. Rate returns the amount of to that one
+// unit of from buys, or an error wrapping ErrNoRate if the pair is not
+// supported.
+type Provider interface {
+	Rate(ctx context.Context, from, to money.Currency) (money.Rate, error)
+}
+
+// Static is a Provider with fixed rates, given as the amount of each
+// currency one unit of a base currency buys. Rates between two other
+// currencies are crossed through the base. It is intended for local testing.
+type Static struct {
+	rates map[money.Currency]money.Rate
+}
+
+// NewStatic returns a Static provider quoting rates against base
+func NewStatic(base money.Currency, rates map[money.Currency]money.Rate) (*Static, error) {
+	if _, err := money.ParseCurrency(base.String()); err != nil {
+		return nil, err
+	}
+	one := money.MustParseRate("1")
+	s := &Static{rates: map[money.Currency]money.Rate{base: one}}
+	for c, r := range rates {
+		if _, err := money.ParseCurrency(c.String()); err != nil {
+			return nil, err
+		}
+		if r <= 0 {
+			return nil, fmt.Errorf("%w: %s", money.ErrRate, c)
+		}
+		if c == base && r != one {
+			return nil, fmt.Errorf("%w: the base currency %s must have a rate of 1", money.ErrRate, c)
+		}
+		s.rates[c] = r
+	}
+	return s, nil
+}
+
+// staticFile is the format read by LoadFile
+type staticFile struct {
+	Base  money.Currency                `json:"base"`
+	Rates map[money.Currency]money.Rate `json:"rates"`
+}
+
+// LoadFile reads a Static provider from a JSON file of the form
+//
+//	{"base": "USD", "rates": {"EUR": 0.92, "JPY": "149.5"}}
+func LoadFile(path string) (*Static, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f staticFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("fx: %s: %w", path, err)
+	}
+	s, err := NewStatic(f.Base, f.Rates)
+	if err != nil {
+		return nil, fmt.Errorf("fx: %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Rate implements Provider. Crossed rates are rounded to 8 decimal places.
+func (s *Static) Rate(_ context.Context, from, to money.Currency) (money.Rate, error) {
+	fromRate, ok := s.rates[from]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s to %s", ErrNoRate, from, to)
+	}
+	toRate, ok := s.rates[to]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s to %s", ErrNoRate, from, to)
+	}
+	r, err := money.CrossRate(fromRate, toRate)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s to %s: %v", ErrNoRate, from, to, err)
+	}
+	return r, nil
+}
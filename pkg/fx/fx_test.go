@@ -0,0 +1,94 @@
+package fx
+
+import (
+	"chariot-assessment/pkg/money"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var _ Provider = (*Static)(nil)
+
+func TestStatic(t *testing.T) {
+	p, err := NewStatic("USD", map[money.Currency]money.Rate{
+		"EUR": money.MustParseRate("0.8"),
+		"JPY": money.MustParseRate("150"),
+	})
+	if err != nil {
+		t.Fatalf("NewStatic: got error %v", err)
+	}
+	tests := []struct {
+		from, to money.Currency
+		want     string
+	}{
+		{"USD", "EUR", "0.8"},
+		{"EUR", "USD", "1.25"},
+		{"EUR", "JPY", "187.5"},
+		{"JPY", "EUR", "0.00533333"},
+		{"USD", "USD", "1"},
+		{"JPY", "JPY", "1"},
+	}
+	for _, tt := range tests {
+		got, err := p.Rate(context.Background(), tt.from, tt.to)
+		if err != nil {
+			t.Fatalf("Rate(%s, %s): got error %v", tt.from, tt.to, err)
+		}
+		if got != money.MustParseRate(tt.want) {
+			t.Fatalf("Rate(%s, %s) = %v, want %s", tt.from, tt.to, got, tt.want)
+		}
+	}
+	if _, err := p.Rate(context.Background(), "USD", "GBP"); !errors.Is(err, ErrNoRate) {
+		t.Fatalf("Rate(USD, GBP): got error %v, want ErrNoRate", err)
+	}
+}
+
+func TestNewStaticInvalid(t *testing.T) {
+	if _, err := NewStatic("XXX", nil); !errors.Is(err, money.ErrCurrency) {
+		t.Fatalf("unknown base: got error %v, want ErrCurrency", err)
+	}
+	if _, err := NewStatic("USD", map[money.Currency]money.Rate{"EURO": 1}); !errors.Is(err, money.ErrCurrency) {
+		t.Fatalf("unknown currency: got error %v, want ErrCurrency", err)
+	}
+	if _, err := NewStatic("USD", map[money.Currency]money.Rate{"EUR": 0}); !errors.Is(err, money.ErrRate) {
+		t.Fatalf("zero rate: got error %v, want ErrRate", err)
+	}
+	if _, err := NewStatic("USD", map[money.Currency]money.Rate{"USD": money.MustParseRate("2")}); !errors.Is(err, money.ErrRate) {
+		t.Fatalf("base rate of 2: got error %v, want ErrRate", err)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	err := os.WriteFile(path, []byte(`{"base": "usd", "rates": {"eur": 0.92, "JPY": "149.5"}}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: got error %v", err)
+	}
+	got, err := p.Rate(context.Background(), "EUR", "JPY")
+	if err != nil {
+		t.Fatalf("Rate(EUR, JPY): got error %v", err)
+	}
+	if got != money.MustParseRate("162.5") {
+		t.Fatalf("Rate(EUR, JPY) = %v, want 162.5", got)
+	}
+
+	for _, body := range []string{`{"base": "USD", "rates": {"EUR": -1}}`, `{"base": "USD", "rates": {"EURO": 1}}`, `{"rates": {}}`, `[`} {
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadFile(path); err == nil {
+			t.Fatalf("Expected %s to be rejected", body)
+		}
+	}
+}
+
+func TestLoadSampleFile(t *testing.T) {
+	if _, err := LoadFile("../../fx_rates.json"); err != nil {
+		t.Fatalf("LoadFile(fx_rates.json): got error %v", err)
+	}
+}
@@ -0,0 +1,243 @@
+package id
+
+import (
+	"fmt"
+)
+
+const (
+	// stdAlphabet is the RFC 4648 Base32 alphabet
+	stdAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+	// crockfordAlphabet is Douglas Crockford's Base32 alphabet, which drops
+	// the easily confused I, L, O and U and lists its symbols in ASCII order
+	crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// checkAlphabet is Crockford's check symbol alphabet. Check values 0-31 use
+// the Crockford Base32 symbols and 32-36 use the five extra symbols.
+const checkAlphabet = crockfordAlphabet + "*~$=U"
+
+// invalidSymbol marks bytes outside the alphabet in a decode map
+const invalidSymbol = 0xFF
+
+// ErrCheckSymbol is returned when the check symbol of a string does not match
+// the ID it follows, or when an Encoding created by WithCheck is given a
+// string without one.
+var ErrCheckSymbol = invalid("check symbol does not match")
+
+var checkDecodeMap = newCheckDecodeMap()
+
+func newCheckDecodeMap() [256]byte {
+	enc := Encoding{}
+	for i := range enc.decodeMap {
+		enc.decodeMap[i] = invalidSymbol
+	}
+	for i := 0; i < len(checkAlphabet); i++ {
+		enc.setSymbol(checkAlphabet[i], byte(i))
+	}
+	for alias, c := range map[byte]byte{'I': '1', 'L': '1', 'O': '0'} {
+		enc.setSymbol(alias, enc.decodeMap[c])
+	}
+	return enc.decodeMap
+}
+
+// An Encoding is a Base32 alphabet for the string form of an ID. Whatever the
+// alphabet, a dash is placed after the seventh character, between the
+// timestamp and the counter.
+//
+// A string may end in a second dash followed by a Crockford mod-37 check
+// symbol, which catches any single mistyped character and any transposition
+// of adjacent characters. Decoding verifies the check symbol whenever one is
+// present; see WithCheck.
+type Encoding struct {
+	alphabet  string
+	decodeMap [256]byte
+	check     bool
+}
+
+var (
+	// StdEncoding uses the RFC 4648 alphabet. It is the encoding used by
+	// ID.String and FromString. Digits sort before letters in ASCII but
+	// after them in this alphabet, so string order does not match byte order.
+	StdEncoding = NewEncoding(stdAlphabet)
+
+	// CrockfordEncoding uses Crockford's Base32 alphabet. On decode I and L
+	// are read as 1, O as 0 and U as V. Because the alphabet is in ASCII
+	// order, comparing the strings of two IDs of the same version gives the
+	// same result as comparing their bytes.
+	CrockfordEncoding = newCrockfordEncoding()
+)
+
+func newCrockfordEncoding() *Encoding {
+	enc := NewEncoding(crockfordAlphabet)
+	for alias, c := range map[byte]byte{'I': '1', 'L': '1', 'O': '0', 'U': 'V'} {
+		enc.setSymbol(alias, enc.decodeMap[c])
+	}
+	return enc
+}
+
+// NewEncoding returns an Encoding defined by the given 32-symbol alphabet.
+// Letters are decoded case-insensitively.
+func NewEncoding(alphabet string) *Encoding {
+	if len(alphabet) != 32 {
+		panic("id: encoding alphabet must be 32 bytes long")
+	}
+	enc := &Encoding{alphabet: alphabet}
+	for i := range enc.decodeMap {
+		enc.decodeMap[i] = invalidSymbol
+	}
+	for i := 0; i < len(alphabet); i++ {
+		c := alphabet[i]
+		if c == '-' || enc.decodeMap[c] != invalidSymbol {
+			panic("id: encoding alphabet contains an invalid or repeated symbol")
+		}
+		enc.setSymbol(c, byte(i))
+	}
+	return enc
+}
+
+// WithCheck returns a copy of enc that appends a check symbol to every string
+// it encodes and requires one on every string it decodes.
+func (enc *Encoding) WithCheck() *Encoding {
+	c := *enc
+	c.check = true
+	return &c
+}
+
+func (enc *Encoding) setSymbol(c, v byte) {
+	enc.decodeMap[c] = v
+	switch {
+	case 'A' <= c && c <= 'Z':
+		enc.decodeMap[c+'a'-'A'] = v
+	case 'a' <= c && c <= 'z':
+		enc.decodeMap[c-'a'+'A'] = v
+	}
+}
+
+// EncodeToString returns the string form of id: 7 characters, a dash, and
+// 11 more characters for V1 or 12 more for V2.
+func (enc *Encoding) EncodeToString(id ID) string {
+	var buf [v2Symbols]byte
+	n := v1Symbols
+	if id.Version() == V2 {
+		n = v2Symbols
+	}
+	encodeBits(enc.alphabet, buf[:n], id[:], n*5)
+
+	s := string(buf[:7]) + "-" + string(buf[7:n])
+	if enc.check {
+		s += "-" + string(checkAlphabet[enc.checksum(buf[:n])])
+	}
+	return s
+}
+
+// DecodeString parses the string form of an ID, with or without its dash.
+// The format is detected from the number of characters excluding the dash
+// and any check symbol: 17 or 18 for V1, and 19 for V2. If a check symbol
+// is present and does not match, the error wraps ErrCheckSymbol.
+func (enc *Encoding) DecodeString(s string) (ID, error) {
+	var (
+		id       ID
+		check    byte
+		hasCheck bool
+	)
+	if n := len(s); n > 2 && s[n-2] == '-' {
+		check, hasCheck = s[n-1], true
+		s = s[:n-2]
+	} else if enc.check {
+		return id, fmt.Errorf("%w: missing check symbol", ErrCheckSymbol)
+	}
+	if len(s) > 7 && s[7] == '-' {
+		s = s[:7] + s[8:]
+	}
+	var dst []byte
+	switch len(s) {
+	case v1Symbols - 1:
+		// legacy form with the final character truncated
+		dst = id[:v1Len-1]
+	case v1Symbols:
+		dst = id[:v1Len]
+	case v2Symbols:
+		dst = id[:v2Len]
+	default:
+		return id, fmt.Errorf("%w: must be 17, 18 or 19 characters excluding the dash", ErrLength)
+	}
+	if !decodeBits(&enc.decodeMap, dst, s) {
+		return id, ErrSymbol
+	}
+	// 18 symbols hold 90 bits, two more than a V1 ID
+	if len(s) == v1Symbols && enc.decodeMap[s[v1Symbols-1]]&0x3 != 0 {
+		return id, ErrPadding
+	}
+	if hasCheck && checkDecodeMap[check] != enc.checksum([]byte(s)) {
+		return id, ErrCheckSymbol
+	}
+	if len(s) == v2Symbols {
+		id[v2Len-1] |= v2Flag
+	}
+	return id, nil
+}
+
+// checksum returns the check value of symbols, which must all be in enc's
+// alphabet: the number they represent modulo 37.
+func (enc *Encoding) checksum(symbols []byte) byte {
+	var r uint
+	for _, c := range symbols {
+		r = (r*32 + uint(enc.decodeMap[c])) % 37
+	}
+	return byte(r)
+}
+
+// encodeBits encodes the leading nbits of src as Base32 symbols, five bits
+// per symbol, most significant bit first. nbits must be a multiple of 5;
+// bits past the end of src are encoded as zero. Unlike encoding/base32 it can
+// stop short of a byte boundary, which lets the 95 significant bits of a V2
+// ID fit in 19 symbols.
+func encodeBits(alphabet string, dst, src []byte, nbits int) {
+	var (
+		buf uint16
+		n   uint
+		j   int
+	)
+	for _, b := range src {
+		buf = buf<<8 | uint16(b)
+		n += 8
+		for n >= 5 && j < nbits/5 {
+			n -= 5
+			dst[j] = alphabet[buf>>n&0x1F]
+			j++
+		}
+	}
+	if n > 0 && j < nbits/5 {
+		dst[j] = alphabet[buf<<(5-n)&0x1F]
+	}
+}
+
+// decodeBits decodes Base32 symbols into dst, most significant bit first.
+// Bits that do not fit in dst are discarded. It reports false if src
+// contains a symbol outside the alphabet.
+func decodeBits(decodeMap *[256]byte, dst []byte, src string) bool {
+	var (
+		buf uint16
+		n   uint
+		j   int
+	)
+	for i := 0; i < len(src); i++ {
+		v := decodeMap[src[i]]
+		if v == invalidSymbol {
+			return false
+		}
+		buf = buf<<5 | uint16(v)
+		n += 5
+		if n >= 8 {
+			n -= 8
+			if j < len(dst) {
+				dst[j] = byte(buf >> n)
+			}
+			j++
+		}
+	}
+	if n > 0 && j < len(dst) {
+		dst[j] = byte(buf << (8 - n))
+	}
+	return true
+}
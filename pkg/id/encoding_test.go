@@ -0,0 +1,210 @@
+package id
+
+import (
+	"bytes"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// randomID returns an arbitrary ID of version v built from b
+func randomID(b [12]byte, v Version) ID {
+	id := ID(b)
+	if v == V2 {
+		id[v2Len-1] |= v2Flag
+	} else {
+		id[v2Len-1] = 0
+	}
+	return id
+}
+
+func TestStdEncodingMatchesBase32(t *testing.T) {
+	f := func(b [12]byte) bool {
+		id := randomID(b, V1)
+		s := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(id[:v1Len])
+		return id.String() == s[:7]+"-"+s[7:]
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCrockfordRoundTrip(t *testing.T) {
+	f := func(b [12]byte, v2 bool) bool {
+		v := V1
+		if v2 {
+			v = V2
+		}
+		id := randomID(b, v)
+		got, err := CrockfordEncoding.DecodeString(CrockfordEncoding.EncodeToString(id))
+		return err == nil && got == id
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCrockfordOrder(t *testing.T) {
+	for _, v := range []Version{V1, V2} {
+		f := func(a, b [12]byte) bool {
+			x, y := randomID(a, v), randomID(b, v)
+			return bytes.Compare(x[:], y[:]) ==
+				strings.Compare(CrockfordEncoding.EncodeToString(x), CrockfordEncoding.EncodeToString(y))
+		}
+		if err := quick.Check(f, &quick.Config{MaxCount: 10000}); err != nil {
+			t.Fatalf("version %d: %v", v, err)
+		}
+	}
+}
+
+func TestCrockfordGeneratedOrder(t *testing.T) {
+	for _, v := range []Version{V1, V2} {
+		g := newTestGenerator(t, WithVersion(v))
+		var last string
+		for i := 0; i < 1000; i++ {
+			next, err := g.New()
+			if err != nil {
+				t.Fatalf("Failed to generate ID: got error %v", err)
+			}
+			s := CrockfordEncoding.EncodeToString(next)
+			if s <= last {
+				t.Fatalf("Next ID string not monotonic: (last, new) = (%v, %v)", last, s)
+			}
+			last = s
+		}
+	}
+}
+
+func TestCrockfordAliases(t *testing.T) {
+	id, err := CrockfordEncoding.DecodeString("01VYZ0A-1BMK1XQP00R")
+	if err != nil {
+		t.Fatalf("Failed to parse string: got error %v", err)
+	}
+	for _, s := range []string{
+		"01vyz0a-1bmk1xqp00r",
+		"O1VYZOA-1BMK1XQPOOR",
+		"oIUYZ0A-lBMKLXQP00R",
+	} {
+		got, err := CrockfordEncoding.DecodeString(s)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: got error %v", s, err)
+		}
+		if got != id {
+			t.Fatalf("DecodeString(%q) = %v, want %v", s, got, id)
+		}
+	}
+	if s := CrockfordEncoding.EncodeToString(id); strings.ContainsAny(s, "ILOU") {
+		t.Fatalf("Crockford encoding must not emit I, L, O or U: got %q", s)
+	}
+}
+
+func TestDecodeInvalidSymbol(t *testing.T) {
+	for _, tc := range []struct {
+		enc *Encoding
+		s   string
+	}{
+		{StdEncoding, "BC5YUGA-AAC3RCLSSV1"},
+		{StdEncoding, "BC5YUGA-AAC3RCLSSV*"},
+		{CrockfordEncoding, "01VYZ0A-1BMKLXQP00*"},
+	} {
+		if _, err := tc.enc.DecodeString(tc.s); err == nil {
+			t.Fatalf("Expected %q to be rejected", tc.s)
+		}
+	}
+}
+
+func TestCheckSymbol(t *testing.T) {
+	checked := StdEncoding.WithCheck()
+	for _, v := range []Version{V1, V2} {
+		g := newTestGenerator(t, WithVersion(v))
+		for i := 0; i < 100; i++ {
+			want, err := g.New()
+			if err != nil {
+				t.Fatalf("Failed to generate ID: got error %v", err)
+			}
+			s := checked.EncodeToString(want)
+			if !strings.HasPrefix(s, want.String()+"-") || len(s) != len(want.String())+2 {
+				t.Fatalf("got %q, want %q followed by a dash and check symbol", s, want.String())
+			}
+			for _, in := range []string{s, strings.ToLower(s), strings.Replace(s, "-", "", 1)} {
+				for _, enc := range []*Encoding{checked, StdEncoding} {
+					got, err := enc.DecodeString(in)
+					if err != nil {
+						t.Fatalf("Failed to parse %q: got error %v", in, err)
+					}
+					if got != want {
+						t.Fatalf("DecodeString(%q) = %v, want %v", in, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestCheckSymbolRequired(t *testing.T) {
+	id, err := New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	if _, err := StdEncoding.WithCheck().DecodeString(id.String()); !errors.Is(err, ErrCheckSymbol) {
+		t.Fatalf("got %v, want %v", err, ErrCheckSymbol)
+	}
+}
+
+func TestCheckSymbolDetectsTypos(t *testing.T) {
+	for _, enc := range []*Encoding{StdEncoding.WithCheck(), CrockfordEncoding.WithCheck()} {
+		id, err := New()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: got error %v", err)
+		}
+		s := enc.EncodeToString(id)
+		for i := 0; i < len(s); i++ {
+			if s[i] == '-' {
+				continue
+			}
+			// every single-character substitution
+			for _, c := range []byte(enc.alphabet + checkAlphabet) {
+				typo := []byte(s)
+				typo[i] = c
+				decodeMap := &enc.decodeMap
+				if i == len(s)-1 {
+					decodeMap = &checkDecodeMap
+				}
+				if decodeMap[c] == decodeMap[s[i]] {
+					// the same symbol, or an alias of it
+					continue
+				}
+				if got, err := enc.DecodeString(string(typo)); err == nil {
+					t.Fatalf("typo %q of %q decoded as %v", typo, s, got)
+				}
+			}
+			// every transposition of adjacent characters
+			if i+1 < len(s) && s[i+1] != '-' && s[i] != s[i+1] {
+				typo := []byte(s)
+				typo[i], typo[i+1] = typo[i+1], typo[i]
+				if got, err := enc.DecodeString(string(typo)); err == nil {
+					t.Fatalf("transposition %q of %q decoded as %v", typo, s, got)
+				}
+			}
+		}
+	}
+}
+
+func TestFromStringCheckSymbol(t *testing.T) {
+	id, err := New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	s := []byte(StdEncoding.WithCheck().EncodeToString(id))
+	// corrupt the check symbol itself
+	if s[len(s)-1] == '0' {
+		s[len(s)-1] = '1'
+	} else {
+		s[len(s)-1] = '0'
+	}
+	if _, err := FromString(string(s)); !errors.Is(err, ErrCheckSymbol) {
+		t.Fatalf("got %v, want %v", err, ErrCheckSymbol)
+	}
+}
@@ -0,0 +1,72 @@
+package id
+
+import (
+	"fmt"
+	"io"
+)
+
+// defaultEntropyBuffer is the number of random bytes a Generator reads from
+// its entropy source at a time: enough for 819 IDs.
+const defaultEntropyBuffer = 4096
+
+// entropyPool hands out random bytes from a buffer refilled in bulk from r,
+// amortizing the cost of each read from the source across many IDs. Every
+// byte is handed out at most once, so IDs draw from the source exactly as
+// they would reading it directly. An entropyPool is not safe for concurrent
+// use; a Generator guards its pool with its mutex.
+type entropyPool struct {
+	r   io.Reader
+	buf []byte
+	// off is the index of the first unused byte in buf
+	off int
+	// size is the number of bytes read per refill, or zero to read only
+	// as many as each request needs
+	size int
+}
+
+// newEntropyPool returns a pool buffering size bytes of r. A size of zero
+// reads r once for every request.
+func newEntropyPool(r io.Reader, size int) *entropyPool {
+	return &entropyPool{r: r, buf: make([]byte, size), off: size, size: size}
+}
+
+// read fills b with unused random bytes, refilling the buffer from the
+// source whenever it runs out. Bytes are handed out in the order the source
+// produced them.
+func (p *entropyPool) read(b []byte) error {
+	for len(b) > 0 {
+		if p.off == len(p.buf) {
+			if err := p.refill(len(b)); err != nil {
+				return err
+			}
+		}
+		n := copy(b, p.buf[p.off:])
+		// wipe handed out bytes so they cannot be recovered from the buffer
+		for i := p.off; i < p.off+n; i++ {
+			p.buf[i] = 0
+		}
+		p.off += n
+		b = b[n:]
+	}
+	return nil
+}
+
+// refill reads a fresh buffer from the source: size bytes, or n if the pool
+// is unbuffered. Reading into the pool's own buffer rather than the caller's
+// keeps IDs from escaping to the heap through the io.Reader interface.
+func (p *entropyPool) refill(n int) error {
+	if p.size > 0 {
+		n = p.size
+	}
+	if cap(p.buf) < n {
+		p.buf = make([]byte, n)
+	}
+	p.buf = p.buf[:n]
+	if _, err := io.ReadFull(p.r, p.buf); err != nil {
+		// don't hand out a partial fill
+		p.off = len(p.buf)
+		return fmt.Errorf("failed to generate random bytes: %v", err)
+	}
+	p.off = 0
+	return nil
+}
@@ -0,0 +1,143 @@
+package id
+
+import (
+	"bytes"
+	"testing"
+)
+
+// countingReader returns the bytes 0, 1, 2, ... and records each read
+type countingReader struct {
+	next  byte
+	reads int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.reads++
+	for i := range p {
+		p[i] = r.next
+		r.next++
+	}
+	return len(p), nil
+}
+
+// The entropy benchmarks borrow seconds once the counter is exhausted, so
+// they measure the cost of minting an ID rather than the counter's limit of
+// 65,536 IDs per second.
+
+func BenchmarkEntropyBuffered(b *testing.B) {
+	benchmarkEntropy(b, defaultEntropyBuffer)
+}
+
+func BenchmarkEntropyUnbuffered(b *testing.B) {
+	benchmarkEntropy(b, 0)
+}
+
+func BenchmarkEntropyBufferedParallel(b *testing.B) {
+	benchmarkEntropyParallel(b, defaultEntropyBuffer)
+}
+
+func BenchmarkEntropyUnbufferedParallel(b *testing.B) {
+	benchmarkEntropyParallel(b, 0)
+}
+
+func benchmarkEntropy(b *testing.B, size int) {
+	g := newTestGenerator(b, WithEntropyBuffer(size), WithOverflowPolicy(OverflowBorrow))
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		g.New()
+	}
+}
+
+func benchmarkEntropyParallel(b *testing.B, size int) {
+	g := newTestGenerator(b, WithEntropyBuffer(size), WithOverflowPolicy(OverflowBorrow))
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			g.New()
+		}
+	})
+}
+
+func TestEntropyPool(t *testing.T) {
+	r := &countingReader{}
+	p := newEntropyPool(r, 12)
+
+	// bytes are handed out in source order, each exactly once
+	var got []byte
+	for i := 0; i < 2; i++ {
+		b := make([]byte, 5)
+		if err := p.read(b); err != nil {
+			t.Fatalf("Failed to read: got error %v", err)
+		}
+		got = append(got, b...)
+	}
+	if want := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}; !bytes.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if r.reads != 1 {
+		t.Fatalf("got %d reads from the source, want 1", r.reads)
+	}
+
+	// the remaining 2 bytes are used before refilling
+	b := make([]byte, 5)
+	if err := p.read(b); err != nil {
+		t.Fatalf("Failed to read: got error %v", err)
+	}
+	if want := []byte{10, 11, 12, 13, 14}; !bytes.Equal(b, want) {
+		t.Fatalf("got %v, want %v", b, want)
+	}
+	if r.reads != 2 {
+		t.Fatalf("got %d reads from the source, want 2", r.reads)
+	}
+	// used bytes do not linger in the buffer
+	if !bytes.Equal(p.buf[:3], make([]byte, 3)) {
+		t.Fatalf("got %v left in the buffer, want zeros", p.buf[:3])
+	}
+}
+
+func TestEntropyPoolUnbuffered(t *testing.T) {
+	r := &countingReader{}
+	p := newEntropyPool(r, 0)
+	for i := 0; i < 3; i++ {
+		if err := p.read(make([]byte, 5)); err != nil {
+			t.Fatalf("Failed to read: got error %v", err)
+		}
+	}
+	if r.reads != 3 {
+		t.Fatalf("got %d reads from the source, want 3", r.reads)
+	}
+}
+
+func TestEntropyPoolError(t *testing.T) {
+	p := newEntropyPool(errReader{}, 16)
+	if err := p.read(make([]byte, 5)); err == nil {
+		t.Fatal("Expected source failure to be returned")
+	}
+	// a failed refill leaves nothing to hand out
+	if err := p.read(make([]byte, 5)); err == nil {
+		t.Fatal("Expected source failure to be returned")
+	}
+}
+
+func TestEntropyBuffer(t *testing.T) {
+	if _, err := NewGenerator(WithEntropyBuffer(-1)); err == nil {
+		t.Fatal("Expected negative buffer size to be rejected")
+	}
+	// buffering does not change the random bits a seeded generator uses
+	clk := newFakeClock(DefaultEpoch)
+	a := newClockedGenerator(t, clk, WithSeed(1))
+	b := newClockedGenerator(t, clk, WithSeed(1), WithEntropyBuffer(0))
+	for i := 0; i < 2000; i++ {
+		x, err := a.New()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: got error %v", err)
+		}
+		y, err := b.New()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: got error %v", err)
+		}
+		if !bytes.Equal(x.Entropy(), y.Entropy()) {
+			t.Fatalf("ID %d: got entropy %x buffered, %x unbuffered", i, x.Entropy(), y.Entropy())
+		}
+	}
+}
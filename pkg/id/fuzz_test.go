@@ -0,0 +1,229 @@
+package id
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// The fuzz targets run their seed corpus, including the checked-in inputs
+// under testdata/fuzz, as part of go test. To search for new failures:
+//
+//	go test -run=^$ -fuzz=FuzzFromString ./pkg/id
+//
+// and check any input it writes to testdata/fuzz in with the fix.
+
+func FuzzFromString(f *testing.F) {
+	for _, s := range []string{
+		"BC5YUGA-AATBBDEINA",
+		"BC5YUGAAATBBDEINA",
+		"bc5yuga-aatbbdeinaa",
+		"7777777-777777777777",
+		"AAAAAAA-AAAAAAAAAAA-A",
+		"BC5YUGA-AATBBDEINAB",
+		"BC5YUGA--AATBBDEINA",
+		"-",
+		"",
+		"0190a5d2-9c00-7000-8000-00000000000g",
+		"0190a5d2-9c00-4000-8000-000000000001",
+	} {
+		f.Add(s)
+	}
+	// every input is parsed with UUID text rejected, then accepted
+	defer SetUUIDText(false)
+	f.Fuzz(func(t *testing.T, s string) {
+		for _, uuidText := range []bool{false, true} {
+			SetUUIDText(uuidText)
+			fuzzFromString(t, s)
+		}
+	})
+}
+
+// fuzzFromString checks FromString on s with the current SetUUIDText
+// setting
+func fuzzFromString(t *testing.T, s string) {
+	id, err := FromString(s)
+	if err != nil {
+		if !errors.Is(err, ErrInvalid) {
+			t.Fatalf("FromString(%q) with UUID text %v: error %v does not match ErrInvalid", s, GetUUIDText(), err)
+		}
+		return
+	}
+	// whatever was accepted has a canonical form that parses back to
+	// the same ID
+	canonical := id.String()
+	got, err := FromString(canonical)
+	if err != nil {
+		t.Fatalf("FromString(%q) = %v, but its String %q fails: %v", s, id, canonical, err)
+	}
+	if got != id {
+		t.Fatalf("FromString(%q) = %v, want %v", canonical, got, id)
+	}
+	if _, err := parseCanonical(canonical); err != nil {
+		t.Fatalf("String %q of %v is not canonical: %v", canonical, id, err)
+	}
+	if got.String() != canonical {
+		t.Fatalf("String not idempotent: %q then %q", canonical, got.String())
+	}
+}
+
+func FuzzFromBytes(f *testing.F) {
+	f.Add(make([]byte, 11))
+	f.Add(append(make([]byte, 11), 0x01))
+	f.Add(append(make([]byte, 11), 0xFE))
+	f.Add(bytes.Repeat([]byte{0xFF}, 12))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		id, err := FromBytes(b)
+		if err != nil {
+			if !errors.Is(err, ErrInvalid) {
+				t.Fatalf("FromBytes(%x): error %v does not match ErrInvalid", b, err)
+			}
+			return
+		}
+		if !bytes.Equal(id.Bytes(), b) {
+			t.Fatalf("FromBytes(%x).Bytes() = %x", b, id.Bytes())
+		}
+		if wantV := Version(len(b) - v1Len + 1); id.Version() != wantV {
+			t.Fatalf("FromBytes(%x) has version %d, want %d", b, id.Version(), wantV)
+		}
+		got, err := FromString(id.String())
+		if err != nil || got != id {
+			t.Fatalf("FromString(%q) = %v, %v, want %v, nil", id.String(), got, err, id)
+		}
+	})
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	f.Add(make([]byte, 12), false)
+	f.Add(bytes.Repeat([]byte{0xFF}, 12), true)
+	f.Add([]byte("chariot-test"), false)
+	f.Fuzz(func(t *testing.T, b []byte, v2 bool) {
+		var raw [12]byte
+		copy(raw[:], b)
+		v := V1
+		if v2 {
+			v = V2
+		}
+		id := randomID(raw, v)
+
+		for _, enc := range []*Encoding{
+			StdEncoding,
+			StdEncoding.WithCheck(),
+			CrockfordEncoding,
+			CrockfordEncoding.WithCheck(),
+		} {
+			s := enc.EncodeToString(id)
+			for _, in := range []string{s, strings.ToLower(s)} {
+				got, err := enc.DecodeString(in)
+				if err != nil {
+					t.Fatalf("DecodeString(%q): got error %v", in, err)
+				}
+				if got != id {
+					t.Fatalf("DecodeString(%q) = %x, want %x", in, got, id)
+				}
+			}
+			if again := enc.EncodeToString(id); again != s {
+				t.Fatalf("EncodeToString not deterministic: %q then %q", s, again)
+			}
+		}
+		if got, err := FromBytes(id.Bytes()); err != nil || got != id {
+			t.Fatalf("FromBytes(%x) = %v, %v, want %v, nil", id.Bytes(), got, err, id)
+		}
+	})
+}
+
+func FuzzCrockfordOrder(f *testing.F) {
+	f.Add(make([]byte, 12), bytes.Repeat([]byte{0xFF}, 12), false)
+	f.Add([]byte("chariot-abcd"), []byte("chariot-abce"), true)
+	f.Fuzz(func(t *testing.T, a, b []byte, v2 bool) {
+		var rawA, rawB [12]byte
+		copy(rawA[:], a)
+		copy(rawB[:], b)
+		v := V1
+		if v2 {
+			v = V2
+		}
+		x, y := randomID(rawA, v), randomID(rawB, v)
+		sx, sy := CrockfordEncoding.EncodeToString(x), CrockfordEncoding.EncodeToString(y)
+		if got, want := strings.Compare(sx, sy), bytes.Compare(x[:], y[:]); got != want {
+			t.Fatalf("strings %q, %q compare %d, bytes %x, %x compare %d", sx, sy, got, x, y, want)
+		}
+	})
+}
+
+// Property tests
+
+func TestPropertyMonotonic(t *testing.T) {
+	f := func(seed int64, steps []uint8, v2 bool) bool {
+		v := V1
+		if v2 {
+			v = V2
+		}
+		clk := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+		g := newClockedGenerator(t, clk, WithSeed(seed), WithVersion(v), WithOverflowPolicy(OverflowBorrow))
+		var last ID
+		for i, step := range steps {
+			// move the clock forwards or, for odd steps, backwards
+			d := time.Duration(step>>1) * 100 * time.Millisecond
+			if step&1 != 0 {
+				d = -d
+			}
+			clk.Advance(d)
+			next, err := g.New()
+			if err != nil {
+				t.Logf("step %d: %v", i, err)
+				return false
+			}
+			if i > 0 && bytes.Compare(last[:], next[:]) >= 0 {
+				return false
+			}
+			last = next
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPropertyOrder(t *testing.T) {
+	f := func(a, b [12]byte, v2 bool) bool {
+		v := V1
+		if v2 {
+			v = V2
+		}
+		x, y := randomID(a, v), randomID(b, v)
+		byBytes := bytes.Compare(x.Bytes(), y.Bytes())
+		byString := strings.Compare(CrockfordEncoding.EncodeToString(x), CrockfordEncoding.EncodeToString(y))
+		ux, uy := x.UUID(), y.UUID()
+		byUUID := bytes.Compare(ux[:], uy[:])
+		return byBytes == byString && byBytes == byUUID
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPropertyIdempotent(t *testing.T) {
+	f := func(b [12]byte, v2 bool) bool {
+		v := V1
+		if v2 {
+			v = V2
+		}
+		id := randomID(b, v)
+		s := id.String()
+		parsed, err := FromString(s)
+		if err != nil || parsed != id {
+			return false
+		}
+		// format(parse(format(id))) == format(id)
+		return parsed.String() == s
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
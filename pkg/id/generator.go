@@ -0,0 +1,497 @@
+package id
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	mathrand "math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// maxCounterV1 is the largest value the 16-bit V1 counter can hold
+	maxCounterV1 = 1<<16 - 1
+	// maxCounterV2 is the largest value the 24-bit V2 counter can hold
+	maxCounterV2 = 1<<24 - 1
+)
+
+// NodeBits is the number of bits reserved for the node ID by WithNode, taken
+// from the top of the random bits.
+const NodeBits = 10
+
+// MaxNode is the largest node ID that can be passed to WithNode
+const MaxNode = 1<<NodeBits - 1
+
+// ErrCounterExhausted is returned by a Generator using OverflowError when
+// more IDs are requested within a single second than its counter can hold:
+// 65,536 for V1, or 16,777,216 for V2.
+var ErrCounterExhausted = errors.New("id: counter exhausted for the current second")
+
+// ErrClockSkew is returned when the clock lags behind the last issued ID by
+// more than the bound set with WithMaxSkew.
+var ErrClockSkew = errors.New("id: clock skew exceeds maximum")
+
+// ErrBeforeEpoch is returned when the generator's clock reads a time before
+// its epoch, which no timestamp can represent.
+var ErrBeforeEpoch = errors.New("id: clock is before the epoch")
+
+// ErrTimeRange is returned once the generator's clock, or a second borrowed
+// by OverflowBorrow, is past the last second its 32-bit timestamps can
+// represent: 2^32-1 seconds after the epoch.
+var ErrTimeRange = errors.New("id: time is past the representable range")
+
+// ErrInsecureEntropy is returned by NewGenerator when given an entropy source
+// other than crypto/rand without AllowInsecureEntropy.
+var ErrInsecureEntropy = errors.New("id: entropy source is not crypto/rand")
+
+// Clock is a Generator's source of the current time. If a Clock also has a
+// Sleep(time.Duration) method, a Generator using OverflowBlock waits with it
+// instead of time.Sleep, so simulated clocks can advance themselves.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts an ordinary function to the Clock interface
+type ClockFunc func() time.Time
+
+// Now returns f()
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// sleeper is implemented by clocks that control how the generator waits
+type sleeper interface {
+	Sleep(time.Duration)
+}
+
+// systemClock reads the wall clock
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// OverflowPolicy selects what a Generator does once all counter values for
+// the current second have been issued.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for the clock to advance to the next second. If
+	// the clock lags behind the exhausted second, it returns ErrClockSkew
+	// instead, since the wait could be arbitrarily long and would hold up
+	// every caller sharing the generator.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowBorrow continues issuing IDs with the next second's timestamp,
+	// ahead of the wall clock. Later calls keep using the borrowed second
+	// until the clock catches up with it.
+	OverflowBorrow
+	// OverflowError returns ErrCounterExhausted.
+	OverflowError
+)
+
+// Option configures a Generator
+type Option func(*Generator) error
+
+// WithClock replaces the generator's time source, which defaults to the
+// system clock
+func WithClock(c Clock) Option {
+	return func(g *Generator) error {
+		if c == nil {
+			return errors.New("id: clock must not be nil")
+		}
+		g.clock = c
+		g.sleep = time.Sleep
+		if s, ok := c.(sleeper); ok {
+			g.sleep = s.Sleep
+		}
+		return nil
+	}
+}
+
+// WithEntropy replaces the source of the random bits in each ID, which
+// defaults to crypto/rand.Reader. Any other source is refused unless
+// AllowInsecureEntropy is also given, since IDs built from predictable bits
+// can be guessed.
+func WithEntropy(r io.Reader) Option {
+	return func(g *Generator) error {
+		if r == nil {
+			return errors.New("id: entropy source must not be nil")
+		}
+		g.entropy = r
+		return nil
+	}
+}
+
+// WithEntropyBuffer sets how many random bytes the generator reads from its
+// entropy source at a time, which defaults to 4096. Buffering amortizes the
+// cost of each read from crypto/rand across many IDs without weakening
+// them, since every buffered byte is used at most once. A size of zero reads
+// the source once per ID.
+func WithEntropyBuffer(size int) Option {
+	return func(g *Generator) error {
+		if size < 0 {
+			return errors.New("id: entropy buffer size must not be negative")
+		}
+		g.bufferSize = size
+		return nil
+	}
+}
+
+// AllowInsecureEntropy permits entropy sources other than crypto/rand
+func AllowInsecureEntropy() Option {
+	return func(g *Generator) error {
+		g.allowInsecure = true
+		return nil
+	}
+}
+
+// WithSeed makes the generator deterministic for tests and replays: its
+// random bits are drawn from a math/rand source seeded with seed, so two
+// generators with the same seed and clock readings issue the same IDs.
+// It implies AllowInsecureEntropy and must never be used in production.
+func WithSeed(seed int64) Option {
+	return func(g *Generator) error {
+		g.entropy = mathrand.New(mathrand.NewSource(seed))
+		g.allowInsecure = true
+		return nil
+	}
+}
+
+// WithEpoch sets the instant from which the generator counts its timestamps,
+// which defaults to DefaultEpoch. Only whole seconds are kept. IDs minted
+// from different epochs are not comparable, so the epoch must not change
+// for the lifetime of a deployment; decode their times with ID.TimeFrom.
+func WithEpoch(epoch time.Time) Option {
+	return func(g *Generator) error {
+		if epoch.IsZero() {
+			return errors.New("id: epoch must not be the zero time")
+		}
+		g.epoch = time.Unix(epoch.Unix(), 0).UTC()
+		return nil
+	}
+}
+
+// WithOverflowPolicy sets the behaviour once the counter is exhausted.
+// The default is OverflowBlock.
+func WithOverflowPolicy(p OverflowPolicy) Option {
+	return func(g *Generator) error {
+		switch p {
+		case OverflowBlock, OverflowBorrow, OverflowError:
+			g.overflow = p
+			return nil
+		}
+		return fmt.Errorf("id: unknown overflow policy %d", p)
+	}
+}
+
+// WithMaxSkew makes the generator refuse to issue IDs while its clock lags
+// more than d behind the last issued ID. A bound of zero, the default,
+// tolerates any amount of skew.
+func WithMaxSkew(d time.Duration) Option {
+	return func(g *Generator) error {
+		if d < 0 {
+			return errors.New("id: maximum skew must not be negative")
+		}
+		g.maxSkew = d
+		return nil
+	}
+}
+
+// WithSkewHandler registers fn to be called with the observed skew every
+// time the generator finds its clock behind the last issued ID, whether
+// because the clock stepped backwards or because seconds were borrowed by
+// OverflowBorrow. fn is called with the generator locked and must not call
+// back into it.
+func WithSkewHandler(fn func(skew time.Duration)) Option {
+	return func(g *Generator) error {
+		g.onSkew = fn
+		return nil
+	}
+}
+
+// WithVersion selects the format of generated IDs. The default is V1.
+func WithVersion(v Version) Option {
+	return func(g *Generator) error {
+		switch v {
+		case V1:
+			g.maxCounter = maxCounterV1
+		case V2:
+			g.maxCounter = maxCounterV2
+		default:
+			return fmt.Errorf("id: unknown version %d", v)
+		}
+		g.version = v
+		return nil
+	}
+}
+
+// WithNode reserves NodeBits of each ID for node, leaving 30 random bits in
+// V1 IDs and 29 in V2 IDs.
+// Generators with distinct node IDs can never produce the same ID, since
+// two IDs sharing a timestamp and counter still differ in their node bits.
+func WithNode(node uint16) Option {
+	return func(g *Generator) error {
+		if node > MaxNode {
+			return fmt.Errorf("id: node %d out of range [0, %d]", node, MaxNode)
+		}
+		g.node = node
+		g.hasNode = true
+		return nil
+	}
+}
+
+// Generator mints IDs from its own clock, counter and entropy source.
+// A Generator is safe for concurrent use; IDs returned by successive calls
+// to New are strictly increasing in byte order.
+type Generator struct {
+	mu            sync.Mutex
+	clock         Clock
+	sleep         func(time.Duration)
+	entropy       io.Reader
+	allowInsecure bool
+	bufferSize    int
+	pool          *entropyPool
+	epoch         time.Time
+	version       Version
+	overflow      OverflowPolicy
+	maxSkew       time.Duration
+	onSkew        func(time.Duration)
+	node          uint16
+	hasNode       bool
+
+	// high-water timestamp of the most recently issued ID, and the next
+	// counter value to hand out within it. counter exceeds maxCounter once
+	// exhausted.
+	last       uint32
+	counter    uint32
+	maxCounter uint32
+	// how far the clock lagged behind last when it was last read
+	skew time.Duration
+}
+
+// NewGenerator returns a Generator backed by the system clock and crypto/rand
+func NewGenerator(opts ...Option) (*Generator, error) {
+	g := &Generator{
+		clock:      systemClock{},
+		sleep:      time.Sleep,
+		entropy:    rand.Reader,
+		bufferSize: defaultEntropyBuffer,
+		epoch:      DefaultEpoch,
+		version:    V1,
+		maxCounter: maxCounterV1,
+	}
+	for _, opt := range opts {
+		if err := opt(g); err != nil {
+			return nil, err
+		}
+	}
+	if g.entropy != rand.Reader && !g.allowInsecure {
+		return nil, ErrInsecureEntropy
+	}
+	g.pool = newEntropyPool(g.entropy, g.bufferSize)
+	return g, nil
+}
+
+// New generates a cryptographically secure ID in the generator's format
+func (g *Generator) New() (ID, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ts, counter, _, err := g.reserve(1)
+	if err != nil {
+		return ID{}, err
+	}
+	return g.mint(ts, counter)
+}
+
+// NewBatch generates n IDs, in strictly increasing order. See Fill.
+func (g *Generator) NewBatch(n int) ([]ID, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("id: negative batch size %d", n)
+	}
+	ids := make([]ID, n)
+	if err := g.Fill(ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Fill generates len(ids) IDs into ids, in strictly increasing order and
+// sorting after every ID the generator issued before. The generator is
+// locked for the whole batch, reserving counter values a second at a time,
+// so no concurrent call can interleave with it. A batch that runs past the
+// end of a second's counter continues into the next second according to
+// the generator's OverflowPolicy; with OverflowError, Fill returns
+// ErrCounterExhausted without issuing any IDs unless the whole batch fits in
+// the current second.
+func (g *Generator) Fill(ids []ID) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for len(ids) > 0 {
+		ts, first, count, err := g.reserve(uint32(len(ids)))
+		if err != nil {
+			return err
+		}
+		for i := uint32(0); i < count; i++ {
+			if ids[i], err = g.mint(ts, first+i); err != nil {
+				return err
+			}
+		}
+		ids = ids[count:]
+	}
+	return nil
+}
+
+// mint builds the ID for the reserved (timestamp, counter) pair. g.mu must
+// be held.
+func (g *Generator) mint(ts, counter uint32) (ID, error) {
+	var combinedBytes ID
+
+	// seconds since the epoch
+	binary.BigEndian.PutUint32(combinedBytes[:4], ts)
+
+	tail := tailOffset(g.version)
+	if g.version == V2 {
+		// 24-bit counter
+		combinedBytes[4] = byte(counter >> 16)
+		binary.BigEndian.PutUint16(combinedBytes[5:7], uint16(counter))
+	} else {
+		// 16-bit counter
+		binary.BigEndian.PutUint16(combinedBytes[4:6], uint16(counter))
+	}
+
+	// 40-bit random string (39 bits for V2), ~1.1 trillion values
+	if err := g.pool.read(combinedBytes[tail : tail+5]); err != nil {
+		return combinedBytes, err
+	}
+	if g.hasNode {
+		setNode(&combinedBytes, tail, g.node)
+	}
+	if g.version == V2 {
+		combinedBytes[v2Len-1] |= v2Flag
+	}
+	return combinedBytes, nil
+}
+
+// setNode overwrites the top NodeBits of the random data starting at tail
+// with node
+func setNode(id *ID, tail int, node uint16) {
+	id[tail] = byte(node >> (NodeBits - 8))
+	id[tail+1] = byte(node<<(16-NodeBits)) | id[tail+1]&(0xFF>>(NodeBits-8))
+}
+
+// Skew reports how far the generator's clock was behind the last issued ID
+// when it was most recently read, or zero if the clock was caught up.
+func (g *Generator) Skew() time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.skew
+}
+
+// Epoch returns the instant from which the generator counts its timestamps
+func (g *Generator) Epoch() time.Time {
+	return g.epoch
+}
+
+// MinForTime returns the smallest ID in the generator's format and epoch that
+// can be issued during the second containing t
+func (g *Generator) MinForTime(t time.Time) ID {
+	return minForTime(g.version, g.epoch, t)
+}
+
+// MaxForTime returns the largest ID in the generator's format and epoch that
+// can be issued during the second containing t
+func (g *Generator) MaxForTime(t time.Time) ID {
+	return maxForTime(g.version, g.epoch, t)
+}
+
+// timestamp returns the number of seconds between the epoch and t
+func (g *Generator) timestamp(t time.Time) (uint32, error) {
+	secs := t.Unix() - g.epoch.Unix()
+	switch {
+	case secs < 0:
+		return 0, fmt.Errorf("%w: %v is before %v", ErrBeforeEpoch, t.UTC(), g.epoch)
+	case secs > maxTimestamp:
+		return 0, fmt.Errorf("%w: %v is after %v", ErrTimeRange, t.UTC(), g.epoch.Add(maxTimestamp*time.Second))
+	}
+	return uint32(secs), nil
+}
+
+// reserve reserves up to n consecutive counter values within a single
+// second, returning the timestamp, the first counter value and how many were
+// reserved. Fewer than n are reserved when the second's counter runs out.
+// g.mu must be held.
+func (g *Generator) reserve(n uint32) (uint32, uint32, uint32, error) {
+	// reset counter every second. The counter is only reset when the clock
+	// moves past the high-water second; if the clock is behind it, whether
+	// stepped backwards or borrowed from, keep counting from the high-water
+	// mark so IDs never sort before ones already issued.
+	now, err := g.timestamp(g.clock.Now())
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	switch {
+	case now > g.last:
+		g.last = now
+		g.counter = 0
+		g.skew = 0
+	case now < g.last:
+		g.skew = time.Duration(g.last-now) * time.Second
+		if g.onSkew != nil {
+			g.onSkew(g.skew)
+		}
+		if g.maxSkew > 0 && g.skew > g.maxSkew {
+			return 0, 0, 0, fmt.Errorf("%w: clock is %v behind last issued ID", ErrClockSkew, g.skew)
+		}
+	default:
+		g.skew = 0
+	}
+
+	// with OverflowError a batch is issued in full or not at all
+	exhausted := g.counter > g.maxCounter ||
+		g.overflow == OverflowError && g.maxCounter+1-g.counter < n
+	if exhausted {
+		switch g.overflow {
+		case OverflowBorrow:
+			if g.last == maxTimestamp {
+				return 0, 0, 0, fmt.Errorf("%w: cannot borrow the next second", ErrTimeRange)
+			}
+			g.last++
+		case OverflowBlock:
+			for {
+				t := g.clock.Now()
+				now, err := g.timestamp(t)
+				if err != nil {
+					return 0, 0, 0, err
+				}
+				if now > g.last {
+					g.last = now
+					break
+				}
+				if now < g.last {
+					return 0, 0, 0, fmt.Errorf("%w: counter exhausted while clock is %v behind last issued ID", ErrClockSkew, time.Duration(g.last-now)*time.Second)
+				}
+				// sleep until the next wall-clock second
+				g.sleep(time.Second - time.Duration(t.Nanosecond()))
+			}
+		default:
+			if n > 1 {
+				return 0, 0, 0, fmt.Errorf("%w: %d IDs requested, %d left", ErrCounterExhausted, n, g.maxCounter+1-g.counter)
+			}
+			return 0, 0, 0, ErrCounterExhausted
+		}
+		g.counter = 0
+	}
+
+	first := g.counter
+	count := g.maxCounter + 1 - first
+	if count > n {
+		count = n
+	}
+	g.counter += count
+	return g.last, first, count, nil
+}
@@ -0,0 +1,828 @@
+package id
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// These tests are intended to be run with the race detector enabled:
+//
+//	go test -race ./pkg/id
+
+func BenchmarkGeneratorParallel(b *testing.B) {
+	g, err := NewGenerator()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			g.New()
+		}
+	})
+}
+
+// fakeClock is a manually driven clock. Sleeping advances it.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func newFakeClock(t time.Time) *fakeClock {
+	return &fakeClock{t: t}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.Advance(d)
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func newTestGenerator(t testing.TB, opts ...Option) *Generator {
+	t.Helper()
+	g, err := NewGenerator(opts...)
+	if err != nil {
+		t.Fatalf("Failed to create generator: got error %v", err)
+	}
+	return g
+}
+
+// newClockedGenerator returns a generator driven by clk, including any
+// sleeps it performs while blocked on an exhausted counter.
+func newClockedGenerator(t testing.TB, clk *fakeClock, opts ...Option) *Generator {
+	t.Helper()
+	return newTestGenerator(t, append([]Option{WithClock(clk)}, opts...)...)
+}
+
+// mustTimestamp returns the generator's timestamp for now
+func mustTimestamp(t testing.TB, g *Generator, now time.Time) uint32 {
+	t.Helper()
+	ts, err := g.timestamp(now)
+	if err != nil {
+		t.Fatalf("Failed to compute timestamp: got error %v", err)
+	}
+	return ts
+}
+
+// exhaust issues every counter value for the generator's current second
+func exhaust(t *testing.T, g *Generator) ID {
+	t.Helper()
+	var last ID
+	for i := 0; i <= maxCounterV1; i++ {
+		next, err := g.New()
+		if err != nil {
+			t.Fatalf("Failed to generate ID %d: got error %v", i, err)
+		}
+		if i > 0 && bytes.Compare(last[:], next[:]) >= 0 {
+			t.Fatalf("Next ID not monotonic: (last, new) = (%v, %v)", last, next)
+		}
+		last = next
+	}
+	if counter := binary.BigEndian.Uint16(last[4:6]); counter != maxCounterV1 {
+		t.Fatalf("got counter %d, want %d", counter, maxCounterV1)
+	}
+	return last
+}
+
+func TestGeneratorMonotonic(t *testing.T) {
+	g := newTestGenerator(t)
+	var last ID
+	for i := 0; i < 1000; i++ {
+		next, err := g.New()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: got error %v", err)
+		}
+		if bytes.Compare(last[:], next[:]) >= 0 {
+			t.Fatalf("Next ID not monotonic: (last, new) = (%v, %v)", last, next)
+		}
+		last = next
+	}
+}
+
+func TestGeneratorConcurrent(t *testing.T) {
+	const (
+		workers   = 32
+		perWorker = 500
+	)
+	g := newTestGenerator(t)
+
+	var wg sync.WaitGroup
+	results := make([][]ID, workers)
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			ids := make([]ID, 0, perWorker)
+			for i := 0; i < perWorker; i++ {
+				next, err := g.New()
+				if err != nil {
+					errs <- err
+					return
+				}
+				ids = append(ids, next)
+			}
+			results[w] = ids
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+
+	// each goroutine must observe strictly increasing IDs
+	all := make([]ID, 0, workers*perWorker)
+	for w, ids := range results {
+		for i := 1; i < len(ids); i++ {
+			if bytes.Compare(ids[i-1][:], ids[i][:]) >= 0 {
+				t.Fatalf("worker %d: ID not monotonic: (last, new) = (%v, %v)", w, ids[i-1], ids[i])
+			}
+		}
+		all = append(all, ids...)
+	}
+
+	// and no two goroutines may ever share a (timestamp, counter) pair
+	sort.Slice(all, func(i, j int) bool {
+		return bytes.Compare(all[i][:], all[j][:]) < 0
+	})
+	for i := 1; i < len(all); i++ {
+		if bytes.Equal(all[i-1][:6], all[i][:6]) {
+			t.Fatalf("duplicate timestamp/counter: %v, %v", all[i-1], all[i])
+		}
+	}
+}
+
+func TestDefaultGeneratorConcurrent(t *testing.T) {
+	const workers = 16
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen = make(map[ID]bool)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				next, err := New()
+				if err != nil {
+					t.Errorf("Failed to generate ID: got error %v", err)
+					return
+				}
+				mu.Lock()
+				if seen[next] {
+					t.Errorf("duplicate ID %v", next)
+				}
+				seen[next] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestOverflowError(t *testing.T) {
+	clk := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	g := newClockedGenerator(t, clk, WithOverflowPolicy(OverflowError))
+
+	last := exhaust(t, g)
+	if _, err := g.New(); !errors.Is(err, ErrCounterExhausted) {
+		t.Fatalf("got %v, want %v", err, ErrCounterExhausted)
+	}
+	// still exhausted on retry within the same second
+	clk.Advance(999 * time.Millisecond)
+	if _, err := g.New(); !errors.Is(err, ErrCounterExhausted) {
+		t.Fatalf("got %v, want %v", err, ErrCounterExhausted)
+	}
+
+	clk.Advance(time.Millisecond)
+	next, err := g.New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID after clock advanced: got error %v", err)
+	}
+	if bytes.Compare(last[:], next[:]) >= 0 {
+		t.Fatalf("Next ID not monotonic: (last, new) = (%v, %v)", last, next)
+	}
+	if counter := binary.BigEndian.Uint16(next[4:6]); counter != 0 {
+		t.Fatalf("got counter %d, want 0", counter)
+	}
+}
+
+func TestOverflowBorrow(t *testing.T) {
+	clk := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	g := newClockedGenerator(t, clk, WithOverflowPolicy(OverflowBorrow))
+	start := clk.Now()
+
+	last := exhaust(t, g)
+	ts := binary.BigEndian.Uint32(last[:4])
+
+	// the second after exhaustion is borrowed from the future
+	last = exhaust(t, g)
+	if got := binary.BigEndian.Uint32(last[:4]); got != ts+1 {
+		t.Fatalf("got timestamp %d, want borrowed timestamp %d", got, ts+1)
+	}
+	next, err := g.New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	if got := binary.BigEndian.Uint32(next[:4]); got != ts+2 {
+		t.Fatalf("got timestamp %d, want borrowed timestamp %d", got, ts+2)
+	}
+	if bytes.Compare(last[:], next[:]) >= 0 {
+		t.Fatalf("Next ID not monotonic: (last, new) = (%v, %v)", last, next)
+	}
+	if !clk.Now().Equal(start) {
+		t.Fatal("OverflowBorrow should never wait for the clock")
+	}
+
+	// once the clock catches up with the borrowed second the counter
+	// continues rather than resetting
+	clk.Advance(2 * time.Second)
+	last = next
+	next, err = g.New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	if bytes.Compare(last[:], next[:]) >= 0 {
+		t.Fatalf("Next ID not monotonic: (last, new) = (%v, %v)", last, next)
+	}
+	if counter := binary.BigEndian.Uint16(next[4:6]); counter != 1 {
+		t.Fatalf("got counter %d, want 1", counter)
+	}
+}
+
+func TestOverflowBlock(t *testing.T) {
+	clk := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 250*int(time.Millisecond), time.UTC))
+	g := newClockedGenerator(t, clk, WithOverflowPolicy(OverflowBlock))
+	start := clk.Now()
+
+	last := exhaust(t, g)
+	next, err := g.New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	if bytes.Compare(last[:], next[:]) >= 0 {
+		t.Fatalf("Next ID not monotonic: (last, new) = (%v, %v)", last, next)
+	}
+	if got, want := clk.Now().Sub(start), 750*time.Millisecond; got != want {
+		t.Fatalf("blocked for %v, want %v", got, want)
+	}
+	if got, want := binary.BigEndian.Uint32(next[:4]), binary.BigEndian.Uint32(last[:4])+1; got != want {
+		t.Fatalf("got timestamp %d, want %d", got, want)
+	}
+	if counter := binary.BigEndian.Uint16(next[4:6]); counter != 0 {
+		t.Fatalf("got counter %d, want 0", counter)
+	}
+}
+
+func TestOverflowBlockSkewed(t *testing.T) {
+	clk := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	g := newClockedGenerator(t, clk, WithOverflowPolicy(OverflowBlock))
+
+	exhaust(t, g)
+	// an hour's NTP step must not block the generator for an hour
+	clk.Advance(-time.Hour)
+	start := clk.Now()
+	if _, err := g.New(); !errors.Is(err, ErrClockSkew) {
+		t.Fatalf("got %v, want %v", err, ErrClockSkew)
+	}
+	if waited := clk.Now().Sub(start); waited != 0 {
+		t.Fatalf("blocked for %v, want 0", waited)
+	}
+
+	// once the clock passes the exhausted second, IDs are issued again
+	clk.Advance(time.Hour + time.Second)
+	if _, err := g.New(); err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+}
+
+func TestOverflowPolicyInvalid(t *testing.T) {
+	if _, err := NewGenerator(WithOverflowPolicy(OverflowPolicy(42))); err == nil {
+		t.Fatal("Expected unknown overflow policy to be rejected")
+	}
+}
+
+func TestClockRegression(t *testing.T) {
+	clk := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	var observed []time.Duration
+	g := newClockedGenerator(t, clk, WithSkewHandler(func(skew time.Duration) {
+		observed = append(observed, skew)
+	}))
+
+	last, err := g.New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+
+	// step the clock backwards, as an NTP correction might
+	clk.Advance(-10 * time.Second)
+	for i := 0; i < 3; i++ {
+		next, err := g.New()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: got error %v", err)
+		}
+		if bytes.Compare(last[:], next[:]) >= 0 {
+			t.Fatalf("Next ID not monotonic: (last, new) = (%v, %v)", last, next)
+		}
+		if got, want := binary.BigEndian.Uint32(next[:4]), binary.BigEndian.Uint32(last[:4]); got != want {
+			t.Fatalf("got timestamp %d, want high-water timestamp %d", got, want)
+		}
+		last = next
+	}
+	if got, want := g.Skew(), 10*time.Second; got != want {
+		t.Fatalf("got skew %v, want %v", got, want)
+	}
+	if len(observed) != 3 || observed[0] != 10*time.Second {
+		t.Fatalf("got observed skew %v, want 3 reports of 10s", observed)
+	}
+
+	// once the clock passes the high-water mark the skew clears
+	clk.Advance(11 * time.Second)
+	next, err := g.New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	if bytes.Compare(last[:], next[:]) >= 0 {
+		t.Fatalf("Next ID not monotonic: (last, new) = (%v, %v)", last, next)
+	}
+	if skew := g.Skew(); skew != 0 {
+		t.Fatalf("got skew %v, want 0", skew)
+	}
+}
+
+func TestMaxSkew(t *testing.T) {
+	clk := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	g := newClockedGenerator(t, clk, WithMaxSkew(5*time.Second))
+
+	if _, err := g.New(); err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+
+	// within the bound IDs are still issued from the high-water mark
+	clk.Advance(-5 * time.Second)
+	if _, err := g.New(); err != nil {
+		t.Fatalf("Failed to generate ID within skew bound: got error %v", err)
+	}
+
+	clk.Advance(-time.Second)
+	if _, err := g.New(); !errors.Is(err, ErrClockSkew) {
+		t.Fatalf("got %v, want %v", err, ErrClockSkew)
+	}
+	if got, want := g.Skew(), 6*time.Second; got != want {
+		t.Fatalf("got skew %v, want %v", got, want)
+	}
+
+	clk.Advance(6 * time.Second)
+	if _, err := g.New(); err != nil {
+		t.Fatalf("Failed to generate ID after clock recovered: got error %v", err)
+	}
+}
+
+func TestMaxSkewInvalid(t *testing.T) {
+	if _, err := NewGenerator(WithMaxSkew(-time.Second)); err == nil {
+		t.Fatal("Expected negative skew bound to be rejected")
+	}
+}
+
+// zeroReader is an entropy source that only returns zeros, so IDs from
+// generators using it differ only in their timestamp, counter and node.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func TestNode(t *testing.T) {
+	for _, node := range []uint16{0, 1, 0x155, MaxNode} {
+		g := newTestGenerator(t, WithNode(node))
+		for i := 0; i < 100; i++ {
+			next, err := g.New()
+			if err != nil {
+				t.Fatalf("Failed to generate ID: got error %v", err)
+			}
+			if got := next.Node(); got != node {
+				t.Fatalf("got node %d, want %d", got, node)
+			}
+		}
+	}
+}
+
+func TestNodeInvalid(t *testing.T) {
+	if _, err := NewGenerator(WithNode(MaxNode + 1)); err == nil {
+		t.Fatal("Expected out of range node to be rejected")
+	}
+}
+
+func TestNodesNeverCollide(t *testing.T) {
+	clk := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	// identical entropy: only the node bits can tell the IDs apart
+	zeros := []Option{WithEntropy(zeroReader{}), AllowInsecureEntropy()}
+	a := newClockedGenerator(t, clk, append(zeros, WithNode(1))...)
+	b := newClockedGenerator(t, clk, append(zeros, WithNode(2))...)
+
+	seen := make(map[ID]uint16)
+	for i := 0; i < 5000; i++ {
+		if i%1000 == 0 {
+			clk.Advance(time.Second)
+		}
+		for _, g := range []*Generator{a, b} {
+			next, err := g.New()
+			if err != nil {
+				t.Fatalf("Failed to generate ID: got error %v", err)
+			}
+			if node, ok := seen[next]; ok {
+				t.Fatalf("ID %v from node %d collides with node %d", next, g.node, node)
+			}
+			seen[next] = g.node
+		}
+	}
+}
+
+func TestNodesConcurrent(t *testing.T) {
+	const nodes = 8
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen = make(map[ID]bool)
+	)
+	for n := uint16(0); n < nodes; n++ {
+		g := newTestGenerator(t, WithNode(n), WithEntropy(zeroReader{}), AllowInsecureEntropy())
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 250; i++ {
+					next, err := g.New()
+					if err != nil {
+						t.Errorf("Failed to generate ID: got error %v", err)
+						return
+					}
+					mu.Lock()
+					if seen[next] {
+						t.Errorf("duplicate ID %v", next)
+					}
+					seen[next] = true
+					mu.Unlock()
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func TestV2Monotonic(t *testing.T) {
+	g := newTestGenerator(t, WithVersion(V2))
+	var last ID
+	for i := 0; i < 1000; i++ {
+		next, err := g.New()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: got error %v", err)
+		}
+		if bytes.Compare(last[:], next[:]) >= 0 {
+			t.Fatalf("Next ID not monotonic: (last, new) = (%v, %v)", last, next)
+		}
+		last = next
+	}
+}
+
+func TestV2Counter(t *testing.T) {
+	clk := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	g := newClockedGenerator(t, clk, WithVersion(V2), WithOverflowPolicy(OverflowError))
+
+	// counter values beyond 16 bits are carried into the third counter byte
+	first, err := g.New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	g.counter = maxCounterV1 + 1
+	next, err := g.New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	if got := next[4:7]; !bytes.Equal(got, []byte{0x01, 0x00, 0x00}) {
+		t.Fatalf("got counter bytes %x, want 010000", got)
+	}
+	if bytes.Compare(first[:], next[:]) >= 0 {
+		t.Fatalf("Next ID not monotonic: (last, new) = (%v, %v)", first, next)
+	}
+
+	g.counter = maxCounterV2
+	last, err := g.New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	if got := last[4:7]; !bytes.Equal(got, []byte{0xFF, 0xFF, 0xFF}) {
+		t.Fatalf("got counter bytes %x, want ffffff", got)
+	}
+	if _, err := g.New(); !errors.Is(err, ErrCounterExhausted) {
+		t.Fatalf("got %v, want %v", err, ErrCounterExhausted)
+	}
+}
+
+func TestV2Node(t *testing.T) {
+	g := newTestGenerator(t, WithVersion(V2), WithNode(MaxNode))
+	next, err := g.New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	if got := next.Node(); got != MaxNode {
+		t.Fatalf("got node %d, want %d", got, MaxNode)
+	}
+	if next.Version() != V2 {
+		t.Fatal("node bits must not clobber the version bit")
+	}
+}
+
+func TestVersionInvalid(t *testing.T) {
+	if _, err := NewGenerator(WithVersion(Version(3))); err == nil {
+		t.Fatal("Expected unknown version to be rejected")
+	}
+}
+
+func TestEpoch(t *testing.T) {
+	if got := newTestGenerator(t).Epoch(); !got.Equal(DefaultEpoch) {
+		t.Fatalf("got epoch %v, want %v", got, DefaultEpoch)
+	}
+	if got, want := DefaultEpoch.Unix(), int64(1577836800); got != want {
+		t.Fatalf("got DefaultEpoch %d, want %d", got, want)
+	}
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	clk := newFakeClock(now)
+	for _, epoch := range []time.Time{DefaultEpoch, LegacyEpoch, now.Add(-time.Hour)} {
+		g := newClockedGenerator(t, clk, WithEpoch(epoch))
+		next, err := g.New()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: got error %v", err)
+		}
+		if got, want := binary.BigEndian.Uint32(next[:4]), uint32(now.Sub(epoch)/time.Second); got != want {
+			t.Fatalf("epoch %v: got timestamp %d, want %d", epoch, got, want)
+		}
+		if got := next.TimeFrom(epoch); !got.Equal(now) {
+			t.Fatalf("epoch %v: got time %v, want %v", epoch, got, now)
+		}
+		if min, max := g.MinForTime(now), g.MaxForTime(now); bytes.Compare(min[:], next[:]) > 0 || bytes.Compare(next[:], max[:]) > 0 {
+			t.Fatalf("epoch %v: %v not within [%v, %v]", epoch, next, min, max)
+		}
+	}
+
+	if _, err := NewGenerator(WithEpoch(time.Time{})); err == nil {
+		t.Fatal("Expected zero epoch to be rejected")
+	}
+}
+
+func TestBeforeEpoch(t *testing.T) {
+	clk := newFakeClock(DefaultEpoch.Add(-time.Second))
+	g := newClockedGenerator(t, clk)
+	if _, err := g.New(); !errors.Is(err, ErrBeforeEpoch) {
+		t.Fatalf("got %v, want %v", err, ErrBeforeEpoch)
+	}
+
+	// the epoch itself is the first representable second
+	clk.Advance(time.Second)
+	next, err := g.New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	if got := next.Time(); !got.Equal(DefaultEpoch) {
+		t.Fatalf("got time %v, want %v", got, DefaultEpoch)
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	last := DefaultEpoch.Add(maxTimestamp * time.Second)
+	clk := newFakeClock(last)
+	g := newClockedGenerator(t, clk, WithOverflowPolicy(OverflowBorrow))
+	next, err := g.New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	if got := next.Time(); !got.Equal(last) {
+		t.Fatalf("got time %v, want %v", got, last)
+	}
+
+	// borrowing would wrap the timestamp back to the epoch
+	g.counter = g.maxCounter + 1
+	if _, err := g.New(); !errors.Is(err, ErrTimeRange) {
+		t.Fatalf("got %v, want %v", err, ErrTimeRange)
+	}
+
+	clk.Advance(time.Second)
+	g = newClockedGenerator(t, clk)
+	if _, err := g.New(); !errors.Is(err, ErrTimeRange) {
+		t.Fatalf("got %v, want %v", err, ErrTimeRange)
+	}
+
+	// bounds outside the range are clamped rather than wrapped
+	if got := V1.MinForTime(clk.t); !got.Time().Equal(last) {
+		t.Fatalf("got bound at %v, want %v", got.Time(), last)
+	}
+	if got := V1.MinForTime(DefaultEpoch.Add(-time.Hour)); !got.Time().Equal(DefaultEpoch) {
+		t.Fatalf("got bound at %v, want %v", got.Time(), DefaultEpoch)
+	}
+}
+
+func TestClockFunc(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	g := newTestGenerator(t, WithClock(ClockFunc(func() time.Time { return now })))
+	next, err := g.New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	if got := next.Time(); !got.Equal(now) {
+		t.Fatalf("got time %v, want %v", got, now)
+	}
+	if _, err := NewGenerator(WithClock(nil)); err == nil {
+		t.Fatal("Expected nil clock to be rejected")
+	}
+}
+
+func TestEntropy(t *testing.T) {
+	if _, err := NewGenerator(WithEntropy(zeroReader{})); !errors.Is(err, ErrInsecureEntropy) {
+		t.Fatalf("got %v, want %v", err, ErrInsecureEntropy)
+	}
+	// options may be given in either order
+	g := newTestGenerator(t, AllowInsecureEntropy(), WithEntropy(zeroReader{}))
+	next, err := g.New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	if got := next.Entropy(); !bytes.Equal(got, make([]byte, 5)) {
+		t.Fatalf("got entropy %x, want zeros", got)
+	}
+	if _, err := NewGenerator(WithEntropy(nil), AllowInsecureEntropy()); err == nil {
+		t.Fatal("Expected nil entropy source to be rejected")
+	}
+
+	g = newTestGenerator(t, WithEntropy(errReader{}), AllowInsecureEntropy())
+	if _, err := g.New(); err == nil {
+		t.Fatal("Expected entropy read failure to be returned")
+	}
+}
+
+// errReader is an entropy source that always fails
+type errReader struct{}
+
+func (errReader) Read(p []byte) (int, error) {
+	return 0, errors.New("entropy unavailable")
+}
+
+func TestSeed(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	generate := func(seed int64) []ID {
+		clk := newFakeClock(start)
+		g := newClockedGenerator(t, clk, WithSeed(seed), WithVersion(V2))
+		ids := make([]ID, 100)
+		for i := range ids {
+			if i%10 == 0 {
+				clk.Advance(time.Second)
+			}
+			next, err := g.New()
+			if err != nil {
+				t.Fatalf("Failed to generate ID: got error %v", err)
+			}
+			ids[i] = next
+		}
+		return ids
+	}
+	a, b, c := generate(1), generate(1), generate(2)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("ID %d: got %v and %v from the same seed", i, a[i], b[i])
+		}
+	}
+	if a[0] == c[0] {
+		t.Fatalf("got %v from different seeds", a[0])
+	}
+}
+
+func TestFill(t *testing.T) {
+	for _, v := range []Version{V1, V2} {
+		clk := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+		g := newClockedGenerator(t, clk, WithVersion(v))
+		before, err := g.New()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: got error %v", err)
+		}
+		ids, err := g.NewBatch(1000)
+		if err != nil {
+			t.Fatalf("Failed to generate batch: got error %v", err)
+		}
+		if len(ids) != 1000 {
+			t.Fatalf("got %d IDs, want 1000", len(ids))
+		}
+		last := before
+		for i, next := range ids {
+			if bytes.Compare(last[:], next[:]) >= 0 {
+				t.Fatalf("ID %d not monotonic: (last, new) = (%v, %v)", i, last, next)
+			}
+			// the batch holds a contiguous counter range
+			if got, want := next.Counter(), uint32(i+1); got != want {
+				t.Fatalf("ID %d: got counter %d, want %d", i, got, want)
+			}
+			if next.Version() != v {
+				t.Fatalf("ID %d: got version %d, want %d", i, next.Version(), v)
+			}
+			last = next
+		}
+		after, err := g.New()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: got error %v", err)
+		}
+		if bytes.Compare(last[:], after[:]) >= 0 {
+			t.Fatalf("Next ID not monotonic: (last, new) = (%v, %v)", last, after)
+		}
+	}
+
+	if ids, err := NewBatch(0); err != nil || len(ids) != 0 {
+		t.Fatalf("got %v, %v, want no IDs", ids, err)
+	}
+	if _, err := NewBatch(-1); err == nil {
+		t.Fatal("Expected negative batch size to be rejected")
+	}
+}
+
+func TestFillStraddle(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, p := range []OverflowPolicy{OverflowBlock, OverflowBorrow} {
+		clk := newFakeClock(start)
+		g := newClockedGenerator(t, clk, WithOverflowPolicy(p))
+		g.last, g.counter = mustTimestamp(t, g, start), maxCounterV1-9
+
+		ids := make([]ID, 25)
+		if err := g.Fill(ids); err != nil {
+			t.Fatalf("Failed to fill batch: got error %v", err)
+		}
+		for i := 1; i < len(ids); i++ {
+			if bytes.Compare(ids[i-1][:], ids[i][:]) >= 0 {
+				t.Fatalf("ID %d not monotonic: (last, new) = (%v, %v)", i, ids[i-1], ids[i])
+			}
+		}
+		// the first 10 exhaust the counter, the rest start the next second
+		if got := ids[9].Counter(); got != maxCounterV1 {
+			t.Fatalf("got counter %d, want %d", got, maxCounterV1)
+		}
+		if got, want := ids[10].Time(), start.Add(time.Second); !got.Equal(want) || ids[10].Counter() != 0 {
+			t.Fatalf("got ID at %v with counter %d, want %v with counter 0", got, ids[10].Counter(), want)
+		}
+		if got := ids[24].Counter(); got != 14 {
+			t.Fatalf("got counter %d, want 14", got)
+		}
+	}
+
+	// batches larger than a whole second's counter span several seconds
+	g := newTestGenerator(t, WithOverflowPolicy(OverflowBorrow))
+	ids, err := g.NewBatch(3 * (maxCounterV1 + 1))
+	if err != nil {
+		t.Fatalf("Failed to generate batch: got error %v", err)
+	}
+	for i := 1; i < len(ids); i++ {
+		if bytes.Compare(ids[i-1][:], ids[i][:]) >= 0 {
+			t.Fatalf("ID %d not monotonic: (last, new) = (%v, %v)", i, ids[i-1], ids[i])
+		}
+	}
+}
+
+func TestFillOverflowError(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	clk := newFakeClock(start)
+	g := newClockedGenerator(t, clk, WithOverflowPolicy(OverflowError))
+	g.last, g.counter = mustTimestamp(t, g, start), maxCounterV1-9
+
+	// the batch doesn't fit, so none of it is issued
+	if _, err := g.NewBatch(11); !errors.Is(err, ErrCounterExhausted) {
+		t.Fatalf("got %v, want %v", err, ErrCounterExhausted)
+	}
+	if g.counter != maxCounterV1-9 {
+		t.Fatalf("got counter %d after failed batch, want %d", g.counter, maxCounterV1-9)
+	}
+	ids, err := g.NewBatch(10)
+	if err != nil {
+		t.Fatalf("Failed to generate batch: got error %v", err)
+	}
+	if got := ids[9].Counter(); got != maxCounterV1 {
+		t.Fatalf("got counter %d, want %d", got, maxCounterV1)
+	}
+	if _, err := g.New(); !errors.Is(err, ErrCounterExhausted) {
+		t.Fatalf("got %v, want %v", err, ErrCounterExhausted)
+	}
+}
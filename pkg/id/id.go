@@ -1,92 +1,256 @@
 package id
 
 import (
-	"bytes"
-	"crypto/rand"
-	"encoding/base32"
 	"encoding/binary"
-	"errors"
 	"fmt"
-	"strings"
 	"time"
 )
 
-type ID [11]byte
+// ID is a time-ordered unique identifier in one of two formats:
+//
+//   - V1: 32-bit timestamp, 16-bit counter and 40 random bits, held in the
+//     first 11 bytes. The final byte is zero.
+//   - V2: 32-bit timestamp, 24-bit counter and 39 random bits, held in the
+//     leading 95 bits. The final bit is always set, marking the format.
+//
+// IDs of the same format sort in creation order by byte value.
+type ID [12]byte
+
+// Version identifies the layout of an ID
+type Version int
+
+const (
+	// V1 is the original 88-bit format, 18 characters in string form
+	V1 Version = 1
+	// V2 is the 96-bit format with a 24-bit counter, 20 characters in
+	// string form
+	V2 Version = 2
+)
+
+const (
+	v1Len = 11
+	v2Len = 12
+
+	// Base32 characters in the string form of each version, excluding the
+	// dash. The 95 significant bits of a V2 ID exclude its version bit.
+	v1Symbols = 18
+	v2Symbols = 19
+	// v2Flag marks the final byte of a V2 ID
+	v2Flag = 0x01
+)
 
 var (
-	last    uint32
-	counter uint16
+	// DefaultEpoch is the instant from which ID timestamps are counted:
+	// midnight, Jan 1 2020 UTC. With 32 bits of seconds, IDs can be issued
+	// until Feb 7 2156.
+	DefaultEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	// LegacyEpoch is the epoch used before it was made configurable: 05:00,
+	// Jan 1 2020 UTC. A timestamp counted from it is 18,000 seconds behind
+	// one counted from DefaultEpoch for the same instant, so the same ID
+	// decodes 5 hours later with it. Deployments holding IDs issued before
+	// the fix should keep using it to decode their times exactly.
+	LegacyEpoch = time.Unix(1577854800, 0).UTC()
 )
 
-// New generates a cryptographically secure, Base32-encoded ID
+// maxTimestamp is the largest number of seconds an ID can hold
+const maxTimestamp = 1<<32 - 1
+
+// defaultGenerator backs the package-level New function. NewGenerator cannot
+// fail when called without options.
+var defaultGenerator, _ = NewGenerator()
+
+// New generates a cryptographically secure, Base32-encoded ID using the
+// package's default Generator
 func New() (ID, error) {
-	var combinedBytes ID
-
-	// seconds since Jan 1 2020
-	now := uint32(time.Now().Unix() - 1577854800)
-	binary.BigEndian.PutUint32(combinedBytes[:4], now)
-	// reset counter every second
-	if last != now {
-		last = now
-		counter = 0
-	}
+	return defaultGenerator.New()
+}
 
-	// 16-bit counter
-	binary.BigEndian.PutUint16(combinedBytes[4:6], uint16(counter))
-	counter++
+// NewBatch generates n IDs in strictly increasing order using the package's
+// default Generator
+func NewBatch(n int) ([]ID, error) {
+	return defaultGenerator.NewBatch(n)
+}
 
-	// 40-bit random string, ~1.1 trillion values
-	if _, err := rand.Read(combinedBytes[6:]); err != nil {
-		return combinedBytes, fmt.Errorf("failed to generate random bytes: %v", err)
+// FromString parses the StdEncoding string form of an ID, with or without
+// its dash. The format is detected from the number of characters excluding
+// the dash: 17 or 18 for V1, and 19 for V2. After SetUUIDText(true) it also
+// accepts the text form of a UUID produced by ID.UUID.
+func FromString(s string) (ID, error) {
+	if GetUUIDText() && isUUIDText(s) {
+		u, err := ParseUUID(s)
+		if err != nil {
+			return ID{}, err
+		}
+		return FromUUID(u)
 	}
-	return combinedBytes, nil
+	return StdEncoding.DecodeString(s)
 }
 
-func FromString(s string) (ID, error) {
+// FromBytes reads an ID from its byte form: 11 bytes for V1, or 12 bytes
+// with the version bit set for V2.
+func FromBytes(b []byte) (ID, error) {
 	var id ID
-	switch len(s) {
-	case 17:
-		// without dash
-		s = strings.ToUpper(s)
-	case 18:
-		// with dash
-		s = strings.ToUpper(s)
-		s = s[:7] + s[8:]
+	switch {
+	case len(b) == v1Len:
+	case len(b) == v2Len && b[v2Len-1]&v2Flag != 0:
 	default:
-		return id, errors.New("Invalid ID: must be 17 or 18 characters")
+		return id, fmt.Errorf("%w: must be %d bytes, or %d with the version bit set", ErrLength, v1Len, v2Len)
 	}
-	data, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(s)
-	if err != nil {
-		return id, errors.New("Invalid ID: could not decode")
-	}
-	copy(id[:], data)
+	copy(id[:], b)
 	return id, nil
 }
 
-func FromBytes(b []byte) (ID, error) {
+// MinForTime returns the smallest V1 ID counted from DefaultEpoch that can be
+// issued during the second containing t. Together with MaxForTime it turns a time range into an ID
+// range, e.g. id BETWEEN MinForTime(from) AND MaxForTime(to), which can be
+// answered from the primary key index alone. Such range scans are only
+// valid where IDs are compared bytewise, as with StorageBinary; see
+// V2.MinForTime for V2 IDs and Generator.MinForTime for other epochs.
+func MinForTime(t time.Time) ID {
+	return V1.MinForTime(t)
+}
+
+// MaxForTime returns the largest V1 ID that can be issued during the second
+// containing t
+func MaxForTime(t time.Time) ID {
+	return V1.MaxForTime(t)
+}
+
+// MinForTime returns the smallest ID of version v counted from DefaultEpoch
+// that can be issued during the second containing t
+func (v Version) MinForTime(t time.Time) ID {
+	return minForTime(v, DefaultEpoch, t)
+}
+
+// MaxForTime returns the largest ID of version v counted from DefaultEpoch
+// that can be issued during the second containing t
+func (v Version) MaxForTime(t time.Time) ID {
+	return maxForTime(v, DefaultEpoch, t)
+}
+
+// minForTime returns the smallest ID of version v issued during the second
+// containing t, counting from epoch. Times outside the range representable
+// from epoch are clamped to it.
+func minForTime(v Version, epoch, t time.Time) ID {
 	var id ID
-	if len(b) != 11 {
-		return id, errors.New("Invalid ID")
+	secs := t.Unix() - epoch.Unix()
+	switch {
+	case secs < 0:
+		secs = 0
+	case secs > maxTimestamp:
+		secs = maxTimestamp
 	}
-	copy(id[:], b)
-	return id, nil
+	binary.BigEndian.PutUint32(id[:4], uint32(secs))
+	if v == V2 {
+		id[v2Len-1] = v2Flag
+	}
+	return id
+}
+
+// maxForTime returns the largest ID of version v issued during the second
+// containing t, counting from epoch
+func maxForTime(v Version, epoch, t time.Time) ID {
+	id := minForTime(v, epoch, t)
+	n := v1Len
+	if v == V2 {
+		n = v2Len
+	}
+	for i := 4; i < n; i++ {
+		id[i] = 0xFF
+	}
+	return id
+}
+
+// Version reports the format of id
+func (id ID) Version() Version {
+	if id[v2Len-1]&v2Flag != 0 {
+		return V2
+	}
+	return V1
+}
+
+// Bytes returns the byte form of id: 11 bytes for V1 or 12 bytes for V2
+func (id ID) Bytes() []byte {
+	if id.Version() == V2 {
+		return id[:v2Len]
+	}
+	return id[:v1Len]
+}
+
+// Time returns the second at which id was issued, in UTC, assuming it was
+// minted by a Generator counting from DefaultEpoch
+func (id ID) Time() time.Time {
+	return id.TimeFrom(DefaultEpoch)
 }
 
-func Validate(id ID) (bool, error) {
-	if bytes.Compare(id[:], make([]byte, 11)) == 0 {
-		return false, errors.New("ID is uninitialized")
+// TimeFrom returns the second at which id was issued, in UTC, by a
+// Generator configured with WithEpoch(epoch)
+func (id ID) TimeFrom(epoch time.Time) time.Time {
+	return time.Unix(epoch.Unix()+int64(binary.BigEndian.Uint32(id[:4])), 0).UTC()
+}
+
+// OrderedTime returns the time of id, assumed to be counted from
+// DefaultEpoch, with sub-second precision derived from its counter. See
+// OrderedTimeFrom.
+func (id ID) OrderedTime() time.Time {
+	return id.OrderedTimeFrom(DefaultEpoch)
+}
+
+// OrderedTimeFrom returns the second at which id was issued by a Generator
+// counting from epoch, plus the counter's share of that second truncated to
+// the microsecond, in UTC. It is a non-decreasing function of ID byte order
+// for IDs of the same version: if a sorts before b then
+// a.OrderedTimeFrom(e) is not after b.OrderedTimeFrom(e). V1 counters are
+// spaced more than a microsecond apart, so V1 IDs from a single Generator
+// map to strictly increasing times; V2 IDs may share a microsecond once
+// more than a million are issued in a second.
+func (id ID) OrderedTimeFrom(epoch time.Time) time.Time {
+	bits := uint(16)
+	if id.Version() == V2 {
+		bits = 24
 	}
-	ts := binary.BigEndian.Uint32(id[:4])
-	now := uint32(time.Now().Unix() - 1577854800)
-	// This may or may not be a sensible way to validate the timestamp portion
-	if ts > now {
-		return false, errors.New("Timestamp is in the future.")
+	micros := uint64(id.Counter()) * 1e6 >> bits
+	return id.TimeFrom(epoch).Add(time.Duration(micros) * time.Microsecond)
+}
+
+// Counter returns the position of id among the IDs issued by its generator
+// within the same second: 16 bits for V1, or 24 bits for V2
+func (id ID) Counter() uint32 {
+	if id.Version() == V2 {
+		return uint32(id[4])<<16 | uint32(binary.BigEndian.Uint16(id[5:7]))
 	}
-	return true, nil
+	return uint32(binary.BigEndian.Uint16(id[4:6]))
 }
 
+// Entropy returns the random portion of id: 5 bytes for V1, or 39 bits for
+// V2 with the version bit cleared. For IDs minted with WithNode, the leading
+// NodeBits are the node rather than random data.
+func (id ID) Entropy() []byte {
+	off := tailOffset(id.Version())
+	entropy := make([]byte, 5)
+	copy(entropy, id[off:off+5])
+	if id.Version() == V2 {
+		entropy[4] &^= v2Flag
+	}
+	return entropy
+}
+
+// Node returns the node ID embedded by a Generator configured with WithNode.
+// For IDs minted without a node it returns the leading random bits.
+func (id ID) Node() uint16 {
+	off := tailOffset(id.Version())
+	return binary.BigEndian.Uint16(id[off:off+2]) >> (16 - NodeBits)
+}
+
+// String returns the StdEncoding string form of id
 func (id ID) String() string {
-	s := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(id[:])
+	return StdEncoding.EncodeToString(id)
+}
 
-	return s[:7] + "-" + s[7:]
+// tailOffset returns the index of the first byte following the counter
+func tailOffset(v Version) int {
+	if v == V2 {
+		return 7
+	}
+	return 6
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -67,3 +68,267 @@ func TestValidate(t *testing.T) {
 		t.Fatal("Expected ID with future timestamp to be invalid")
 	}
 }
+
+func TestRoundTrip(t *testing.T) {
+	for _, v := range []Version{V1, V2} {
+		g, err := NewGenerator(WithVersion(v))
+		if err != nil {
+			t.Fatalf("Failed to create generator: got error %v", err)
+		}
+		for i := 0; i < 100; i++ {
+			want, err := g.New()
+			if err != nil {
+				t.Fatalf("Failed to generate ID: got error %v", err)
+			}
+			if got := want.Version(); got != v {
+				t.Fatalf("got version %d, want %d", got, v)
+			}
+			s := want.String()
+			for _, in := range []string{s, strings.ToLower(s), strings.Replace(s, "-", "", 1)} {
+				got, err := FromString(in)
+				if err != nil {
+					t.Fatalf("Failed to parse %q: got error %v", in, err)
+				}
+				if got != want {
+					t.Fatalf("FromString(%q) = %v, want %v", in, got, want)
+				}
+			}
+			got, err := FromBytes(want.Bytes())
+			if err != nil {
+				t.Fatalf("Failed to read bytes: got error %v", err)
+			}
+			if got != want {
+				t.Fatalf("FromBytes(%x) = %v, want %v", want.Bytes(), got, want)
+			}
+		}
+	}
+}
+
+func TestV2Format(t *testing.T) {
+	g, err := NewGenerator(WithVersion(V2))
+	if err != nil {
+		t.Fatalf("Failed to create generator: got error %v", err)
+	}
+	id, err := g.New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	s := id.String()
+	if len(s) != 20 || s[7] != '-' {
+		t.Fatalf("got %q, want 20 characters with a dash at index 7", s)
+	}
+	if n := len(id.Bytes()); n != 12 {
+		t.Fatalf("got %d bytes, want 12", n)
+	}
+
+	// the 24-bit counter and 39 random bits span the remaining 63 bits
+	var max ID
+	for i := range max {
+		max[i] = 0xFF
+	}
+	if got, want := max.String(), "7777777-777777777777"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if got, err := FromString("7777777-777777777777"); err != nil || got != max {
+		t.Fatalf("got %v, %v, want %v, nil", got, err, max)
+	}
+}
+
+func TestV1Length(t *testing.T) {
+	id, err := New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	if s := id.String(); len(s) != 19 || s[7] != '-' {
+		t.Fatalf("got %q, want 19 characters with a dash at index 7", s)
+	}
+	if n := len(id.Bytes()); n != 11 {
+		t.Fatalf("got %d bytes, want 11", n)
+	}
+}
+
+func TestFromBytesInvalid(t *testing.T) {
+	for _, b := range [][]byte{nil, make([]byte, 10), make([]byte, 12), make([]byte, 13)} {
+		if _, err := FromBytes(b); err == nil {
+			t.Fatalf("Expected %x to be rejected", b)
+		}
+	}
+}
+
+func TestComponents(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	clk := newFakeClock(now.Add(400 * time.Millisecond))
+	for _, v := range []Version{V1, V2} {
+		g := newClockedGenerator(t, clk, WithVersion(v))
+		g.last, g.counter = mustTimestamp(t, g, now), 0x1234
+		next, err := g.New()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: got error %v", err)
+		}
+		if got := next.Time(); !got.Equal(now) {
+			t.Fatalf("got time %v, want %v", got, now)
+		}
+		if got := next.Counter(); got != 0x1234 {
+			t.Fatalf("got counter %#x, want 0x1234", got)
+		}
+		entropy := next.Entropy()
+		if len(entropy) != 5 {
+			t.Fatalf("got %d bytes of entropy, want 5", len(entropy))
+		}
+		off := tailOffset(v)
+		want := append([]byte(nil), next[off:off+5]...)
+		if v == V2 {
+			want[4] &^= v2Flag
+		}
+		if !bytes.Equal(entropy, want) {
+			t.Fatalf("got entropy %x, want %x", entropy, want)
+		}
+		// the accessor returns a copy
+		entropy[0] ^= 0xFF
+		if next[off] == entropy[0] {
+			t.Fatal("Entropy must not alias the ID")
+		}
+	}
+
+	// 24-bit counters use the third counter byte
+	g := newClockedGenerator(t, clk, WithVersion(V2))
+	g.last, g.counter = mustTimestamp(t, g, now), maxCounterV2
+	next, err := g.New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	if got := next.Counter(); got != maxCounterV2 {
+		t.Fatalf("got counter %#x, want %#x", got, maxCounterV2)
+	}
+}
+
+func TestTimeBounds(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, v := range []Version{V1, V2} {
+		clk := newFakeClock(start)
+		g := newClockedGenerator(t, clk, WithVersion(v), WithOverflowPolicy(OverflowError))
+
+		for _, tc := range []struct {
+			counter uint32
+			advance time.Duration
+		}{
+			{0, 0},
+			{1, 999 * time.Millisecond},
+			{g.maxCounter, 999 * time.Millisecond},
+		} {
+			clk.t = start.Add(tc.advance)
+			g.last, g.counter = mustTimestamp(t, g, start), tc.counter
+			next, err := g.New()
+			if err != nil {
+				t.Fatalf("Failed to generate ID: got error %v", err)
+			}
+			min, max := v.MinForTime(clk.t), v.MaxForTime(clk.t)
+			if min.Version() != v || max.Version() != v {
+				t.Fatalf("got bounds of version %d, %d, want %d", min.Version(), max.Version(), v)
+			}
+			if bytes.Compare(min[:], next[:]) > 0 || bytes.Compare(next[:], max[:]) > 0 {
+				t.Fatalf("%v not within [%v, %v]", next, min, max)
+			}
+			if got := min.Time(); !got.Equal(start) {
+				t.Fatalf("got time %v, want %v", got, start)
+			}
+			// the previous and next seconds are excluded
+			if prev := v.MaxForTime(start.Add(-time.Second)); bytes.Compare(prev[:], next[:]) >= 0 {
+				t.Fatalf("%v sorts before MaxForTime of the previous second %v", next, prev)
+			}
+			if after := v.MinForTime(start.Add(time.Second)); bytes.Compare(next[:], after[:]) >= 0 {
+				t.Fatalf("%v sorts after MinForTime of the next second %v", next, after)
+			}
+		}
+	}
+	if MinForTime(start) != V1.MinForTime(start) || MaxForTime(start) != V1.MaxForTime(start) {
+		t.Fatal("package-level bounds should produce V1 IDs")
+	}
+}
+
+func TestKnownTimes(t *testing.T) {
+	for _, tc := range []struct {
+		id           string
+		want, legacy time.Time
+	}{
+		{
+			"AAAAAAA-AAAAAAAAAA",
+			time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2020, 1, 1, 5, 0, 0, 0, time.UTC),
+		},
+		{
+			"BC5YUGA-AATBBDEINA",
+			time.Date(2024, 8, 22, 16, 45, 12, 0, time.UTC),
+			time.Date(2024, 8, 22, 21, 45, 12, 0, time.UTC),
+		},
+		{
+			"7777777-77777777774",
+			time.Date(2156, 2, 7, 6, 28, 15, 0, time.UTC),
+			time.Date(2156, 2, 7, 11, 28, 15, 0, time.UTC),
+		},
+	} {
+		id, err := FromString(tc.id)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: got error %v", tc.id, err)
+		}
+		if got := id.Time(); !got.Equal(tc.want) {
+			t.Fatalf("%s: got time %v, want %v", tc.id, got, tc.want)
+		}
+		if got := id.TimeFrom(LegacyEpoch); !got.Equal(tc.legacy) {
+			t.Fatalf("%s: got legacy time %v, want %v", tc.id, got, tc.legacy)
+		}
+	}
+}
+
+func TestOrderedTime(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, v := range []Version{V1, V2} {
+		clk := newFakeClock(now)
+		g := newClockedGenerator(t, clk, WithVersion(v), WithOverflowPolicy(OverflowBorrow))
+		var last ID
+		var lastTime time.Time
+		for i := 0; i < 3*(maxCounterV1+1); i++ {
+			next, err := g.New()
+			if err != nil {
+				t.Fatalf("Failed to generate ID: got error %v", err)
+			}
+			got := next.OrderedTime()
+			if got.Truncate(time.Second) != next.Time() || got.Nanosecond()%1000 != 0 {
+				t.Fatalf("got %v for ID issued at %v, want the same second in whole microseconds", got, next.Time())
+			}
+			if i > 0 {
+				// strictly increasing for V1, never decreasing for V2
+				if v == V1 && !got.After(lastTime) || got.Before(lastTime) {
+					t.Fatalf("%v at %v does not follow %v at %v", next, got, last, lastTime)
+				}
+			}
+			last, lastTime = next, got
+		}
+	}
+
+	// the last counter value of a second stays within it
+	for _, tc := range []struct {
+		v       Version
+		counter uint32
+		want    time.Duration
+	}{
+		{V1, 0, 0},
+		{V1, 1, 15 * time.Microsecond},
+		{V1, maxCounterV1, 999984 * time.Microsecond},
+		{V2, 17, time.Microsecond},
+		{V2, maxCounterV2, 999999 * time.Microsecond},
+	} {
+		g := newClockedGenerator(t, newFakeClock(now), WithVersion(tc.v))
+		g.last, g.counter = mustTimestamp(t, g, now), tc.counter
+		next, err := g.New()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: got error %v", err)
+		}
+		if got := next.OrderedTime().Sub(now); got != tc.want {
+			t.Fatalf("V%d counter %d: got offset %v, want %v", tc.v, tc.counter, got, tc.want)
+		}
+		if got := next.OrderedTimeFrom(LegacyEpoch).Sub(next.OrderedTime()); got != 5*time.Hour {
+			t.Fatalf("got legacy offset %v, want 5h", got)
+		}
+	}
+}
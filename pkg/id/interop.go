@@ -0,0 +1,297 @@
+package id
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrNotConvertible is returned when converting a UUID or ULID that was
+	// not produced from an ID back into one. Conversions in the other
+	// direction are always lossless.
+	ErrNotConvertible = invalid("value was not converted from an ID")
+	// ErrUUIDSyntax is returned for malformed UUID text
+	ErrUUIDSyntax = invalid("malformed UUID")
+	// ErrULIDSyntax is returned for malformed ULID text
+	ErrULIDSyntax = invalid("malformed ULID")
+)
+
+const (
+	// uuidVersion and uuidVariant mark a UUIDv7 in its seventh and ninth
+	// bytes
+	uuidVersion = 0x70
+	uuidVariant = 0x80
+	// ulidSymbols is the length of a ULID's string form
+	ulidSymbols = 26
+)
+
+// UUID is an RFC 9562 UUID in its 16-byte form.
+//
+// ID.UUID produces version 7 UUIDs: the ID's second as a 48-bit Unix
+// millisecond timestamp, followed by its counter, its random bits and its
+// version bit in the 74 bits left after the UUID version and variant. IDs of
+// the same format keep their order as UUIDs.
+type UUID [16]byte
+
+// ULID is a ULID in its 16-byte form.
+//
+// ID.ULID produces ULIDs whose 48-bit Unix millisecond timestamp holds the
+// ID's second, followed by its counter, its random bits and its version bit
+// in the 80 random bits. IDs of the same format keep their order as ULIDs,
+// in both byte and string form.
+type ULID [16]byte
+
+var uuidText int32
+
+// SetUUIDText makes FromString, and therefore Kind.Parse and the text and
+// JSON decoders, accept the canonical text form of UUIDs produced by
+// ID.UUID, for systems that only pass IDs around as UUIDs. It should be
+// called once at startup.
+func SetUUIDText(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&uuidText, v)
+}
+
+// GetUUIDText reports whether FromString accepts UUID text
+func GetUUIDText() bool {
+	return atomic.LoadInt32(&uuidText) != 0
+}
+
+// NewUUIDv7 generates an ID using the package's default Generator and
+// returns it as a UUIDv7
+func NewUUIDv7() (UUID, error) {
+	return defaultGenerator.NewUUIDv7()
+}
+
+// NewUUIDv7 generates an ID and returns it as a UUIDv7 counted from the
+// generator's epoch. Successive UUIDs are strictly increasing, like the IDs
+// they are converted from.
+func (g *Generator) NewUUIDv7() (UUID, error) {
+	id, err := g.New()
+	if err != nil {
+		return UUID{}, err
+	}
+	return toUUID(id, g.epoch), nil
+}
+
+// UUID converts id, assumed to be counted from DefaultEpoch, to a UUIDv7
+func (id ID) UUID() UUID {
+	return toUUID(id, DefaultEpoch)
+}
+
+// ULID converts id, assumed to be counted from DefaultEpoch, to a ULID
+func (id ID) ULID() ULID {
+	return toULID(id, DefaultEpoch)
+}
+
+// FromUUID converts a UUID produced by ID.UUID back into the ID
+func FromUUID(u UUID) (ID, error) {
+	return fromUUID(u, DefaultEpoch)
+}
+
+// FromULID converts a ULID produced by ID.ULID back into the ID
+func FromULID(u ULID) (ID, error) {
+	return fromULID(u, DefaultEpoch)
+}
+
+// ParseUUID parses the canonical text form of a UUID,
+// xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx, in either case
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if !isUUIDText(s) {
+		return u, fmt.Errorf("%w: must be 36 characters in the form xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx", ErrUUIDSyntax)
+	}
+	hexDigits := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:36]
+	if _, err := hex.Decode(u[:], []byte(hexDigits)); err != nil {
+		return u, fmt.Errorf("%w: could not decode", ErrUUIDSyntax)
+	}
+	return u, nil
+}
+
+// ParseULID parses the 26-character Crockford Base32 form of a ULID, in
+// either case
+func ParseULID(s string) (ULID, error) {
+	var u ULID
+	if len(s) != ulidSymbols {
+		return u, fmt.Errorf("%w: must be %d characters", ErrULIDSyntax, ulidSymbols)
+	}
+	// the 26 symbols hold 130 bits, the first two of which must be zero
+	if v := CrockfordEncoding.decodeMap[s[0]]; v == invalidSymbol || v > 7 {
+		return u, fmt.Errorf("%w: out of range", ErrULIDSyntax)
+	}
+	var buf [17]byte
+	if !decodeBits(&CrockfordEncoding.decodeMap, buf[:], s) {
+		return u, fmt.Errorf("%w: could not decode", ErrULIDSyntax)
+	}
+	for i := range u {
+		u[i] = buf[i]<<2 | buf[i+1]>>6
+	}
+	return u, nil
+}
+
+// String returns the canonical lowercase text form of u
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:36], u[10:16])
+	return string(buf[:])
+}
+
+// Time returns the millisecond embedded in a UUIDv7, in UTC
+func (u UUID) Time() time.Time {
+	return time.UnixMilli(unixMilli(u[:])).UTC()
+}
+
+// String returns the 26-character Crockford Base32 form of u
+func (u ULID) String() string {
+	// shift the 128 bits right by two to encode them as 130
+	var buf [17]byte
+	buf[0] = u[0] >> 2
+	for i := 1; i < len(u); i++ {
+		buf[i] = u[i-1]<<6 | u[i]>>2
+	}
+	buf[16] = u[15] << 6
+	var dst [ulidSymbols]byte
+	encodeBits(crockfordAlphabet, dst[:], buf[:], ulidSymbols*5)
+	return string(dst[:])
+}
+
+// Time returns the millisecond embedded in u, in UTC
+func (u ULID) Time() time.Time {
+	return time.UnixMilli(unixMilli(u[:])).UTC()
+}
+
+// isUUIDText reports whether s has the shape of a canonical UUID
+func isUUIDText(s string) bool {
+	return len(s) == 36 && s[8] == '-' && s[13] == '-' && s[18] == '-' && s[23] == '-'
+}
+
+// unixMilli reads the leading 48-bit millisecond timestamp shared by UUIDv7
+// and ULID
+func unixMilli(b []byte) int64 {
+	return int64(binary.BigEndian.Uint16(b[0:2]))<<32 | int64(binary.BigEndian.Uint32(b[2:6]))
+}
+
+// putUnixMilli writes the second of id as a 48-bit millisecond timestamp
+func putUnixMilli(b []byte, id ID, epoch time.Time) {
+	ms := uint64(id.TimeFrom(epoch).Unix()) * 1000
+	binary.BigEndian.PutUint16(b[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(b[2:6], uint32(ms))
+}
+
+// readUnixMilli reads a timestamp written by putUnixMilli into id
+func readUnixMilli(b []byte, id *ID, epoch time.Time) error {
+	ms := unixMilli(b)
+	secs := ms/1000 - epoch.Unix()
+	if ms%1000 != 0 || secs < 0 || secs > maxTimestamp {
+		return fmt.Errorf("%w: timestamp is not a second since the epoch", ErrNotConvertible)
+	}
+	binary.BigEndian.PutUint32(id[:4], uint32(secs))
+	return nil
+}
+
+// putTail writes counter and 40 bits of entropy into an ID under
+// construction, in V2 layout with its version bit set if v2 is true
+func putTail(id *ID, counter uint32, entropy []byte, v2 bool) error {
+	v := V1
+	if v2 {
+		v = V2
+		if entropy[4]&v2Flag != 0 {
+			return fmt.Errorf("%w: too many random bits", ErrNotConvertible)
+		}
+		id[4] = byte(counter >> 16)
+		binary.BigEndian.PutUint16(id[5:7], uint16(counter))
+	} else {
+		if counter > maxCounterV1 {
+			return fmt.Errorf("%w: counter out of range", ErrNotConvertible)
+		}
+		binary.BigEndian.PutUint16(id[4:6], uint16(counter))
+	}
+	off := tailOffset(v)
+	copy(id[off:off+5], entropy)
+	if v2 {
+		id[v2Len-1] |= v2Flag
+	}
+	return nil
+}
+
+// toUUID packs id into a UUIDv7. After the version nibble, rand_a holds the
+// top 12 bits of the 24-bit counter; rand_b holds the rest of the counter,
+// the 40 random bits, 9 zero bits and the version bit.
+func toUUID(id ID, epoch time.Time) UUID {
+	var u UUID
+	putUnixMilli(u[:], id, epoch)
+	counter := id.Counter()
+	u[6] = uuidVersion | byte(counter>>20)
+	u[7] = byte(counter >> 12)
+
+	entropy := id.Entropy()
+	var e [8]byte
+	copy(e[3:], entropy)
+	randB := uint64(counter&0xFFF)<<50 | binary.BigEndian.Uint64(e[:])<<10
+	if id.Version() == V2 {
+		randB |= 1
+	}
+	binary.BigEndian.PutUint64(u[8:], randB)
+	u[8] |= uuidVariant
+	return u
+}
+
+// fromUUID reverses toUUID
+func fromUUID(u UUID, epoch time.Time) (ID, error) {
+	var id ID
+	if u[6]&0xF0 != uuidVersion || u[8]&0xC0 != uuidVariant {
+		return id, fmt.Errorf("%w: not a version 7 UUID", ErrNotConvertible)
+	}
+	if err := readUnixMilli(u[:], &id, epoch); err != nil {
+		return id, err
+	}
+	randB := binary.BigEndian.Uint64(u[8:]) &^ (0x3 << 62)
+	if randB&0x3FE != 0 {
+		return id, fmt.Errorf("%w: reserved bits are set", ErrNotConvertible)
+	}
+	counter := uint32(u[6]&0x0F)<<20 | uint32(u[7])<<12 | uint32(randB>>50)
+	var e [8]byte
+	binary.BigEndian.PutUint64(e[:], randB>>10&(1<<40-1))
+	return id, putTail(&id, counter, e[3:], randB&1 != 0)
+}
+
+// toULID packs id into a ULID: the 24-bit counter, 40 random bits, 15 zero
+// bits and the version bit follow the timestamp.
+func toULID(id ID, epoch time.Time) ULID {
+	var u ULID
+	putUnixMilli(u[:], id, epoch)
+	counter := id.Counter()
+	u[6], u[7], u[8] = byte(counter>>16), byte(counter>>8), byte(counter)
+	copy(u[9:14], id.Entropy())
+	if id.Version() == V2 {
+		u[15] = 1
+	}
+	return u
+}
+
+// fromULID reverses toULID
+func fromULID(u ULID, epoch time.Time) (ID, error) {
+	var id ID
+	if err := readUnixMilli(u[:], &id, epoch); err != nil {
+		return id, err
+	}
+	if flags := binary.BigEndian.Uint16(u[14:16]); flags > 1 {
+		return id, fmt.Errorf("%w: reserved bits are set", ErrNotConvertible)
+	}
+	counter := uint32(u[6])<<16 | uint32(u[7])<<8 | uint32(u[8])
+	return id, putTail(&id, counter, u[9:14], u[15] == 1)
+}
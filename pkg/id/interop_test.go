@@ -0,0 +1,195 @@
+package id
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUUIDRoundTrip(t *testing.T) {
+	for _, v := range []Version{V1, V2} {
+		g := newTestGenerator(t, WithVersion(v), WithNode(0x2A5))
+		for i := 0; i < 100; i++ {
+			want, err := g.New()
+			if err != nil {
+				t.Fatalf("Failed to generate ID: got error %v", err)
+			}
+			u := want.UUID()
+			// RFC 9562 version 7, variant 10
+			if u[6]>>4 != 7 || u[8]>>6 != 2 {
+				t.Fatalf("%v: got version %d, variant %b", u, u[6]>>4, u[8]>>6)
+			}
+			if !u.Time().Equal(want.Time()) {
+				t.Fatalf("got time %v, want %v", u.Time(), want.Time())
+			}
+			parsed, err := ParseUUID(strings.ToUpper(u.String()))
+			if err != nil || parsed != u {
+				t.Fatalf("ParseUUID(%q) = %v, %v, want %v, nil", u.String(), parsed, err, u)
+			}
+			got, err := FromUUID(parsed)
+			if err != nil {
+				t.Fatalf("Failed to convert %v: got error %v", u, err)
+			}
+			if got != want {
+				t.Fatalf("FromUUID(%v) = %v, want %v", u, got, want)
+			}
+		}
+	}
+}
+
+func TestULIDRoundTrip(t *testing.T) {
+	for _, v := range []Version{V1, V2} {
+		g := newTestGenerator(t, WithVersion(v))
+		for i := 0; i < 100; i++ {
+			want, err := g.New()
+			if err != nil {
+				t.Fatalf("Failed to generate ID: got error %v", err)
+			}
+			u := want.ULID()
+			s := u.String()
+			if len(s) != 26 {
+				t.Fatalf("got %q, want 26 characters", s)
+			}
+			if !u.Time().Equal(want.Time()) {
+				t.Fatalf("got time %v, want %v", u.Time(), want.Time())
+			}
+			parsed, err := ParseULID(strings.ToLower(s))
+			if err != nil || parsed != u {
+				t.Fatalf("ParseULID(%q) = %v, %v, want %v, nil", s, parsed, err, u)
+			}
+			got, err := FromULID(parsed)
+			if err != nil {
+				t.Fatalf("Failed to convert %v: got error %v", s, err)
+			}
+			if got != want {
+				t.Fatalf("FromULID(%v) = %v, want %v", s, got, want)
+			}
+		}
+	}
+}
+
+func TestInteropOrder(t *testing.T) {
+	for _, v := range []Version{V1, V2} {
+		clk := newFakeClock(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+		g := newClockedGenerator(t, clk, WithVersion(v))
+		var lastUUID, lastULID []byte
+		var lastText string
+		for i := 0; i < 1000; i++ {
+			if i%100 == 0 {
+				clk.Advance(time.Second)
+			}
+			u, err := g.NewUUIDv7()
+			if err != nil {
+				t.Fatalf("Failed to generate UUID: got error %v", err)
+			}
+			if bytes.Compare(lastUUID, u[:]) >= 0 {
+				t.Fatalf("Next UUID not monotonic: (last, new) = (%x, %v)", lastUUID, u)
+			}
+			lastUUID = append([]byte(nil), u[:]...)
+
+			next, err := FromUUID(u)
+			if err != nil {
+				t.Fatalf("Failed to convert %v: got error %v", u, err)
+			}
+			l := next.ULID()
+			if bytes.Compare(lastULID, l[:]) >= 0 || lastText >= l.String() {
+				t.Fatalf("Next ULID not monotonic: (last, new) = (%s, %s)", lastText, l)
+			}
+			lastULID, lastText = append([]byte(nil), l[:]...), l.String()
+		}
+	}
+}
+
+func TestUUIDEpoch(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	clk := newFakeClock(now)
+	g := newClockedGenerator(t, clk, WithEpoch(LegacyEpoch))
+	u, err := g.NewUUIDv7()
+	if err != nil {
+		t.Fatalf("Failed to generate UUID: got error %v", err)
+	}
+	// UUIDs carry Unix time, whatever the generator's epoch
+	if !u.Time().Equal(now) {
+		t.Fatalf("got time %v, want %v", u.Time(), now)
+	}
+}
+
+func TestNotConvertible(t *testing.T) {
+	// generated elsewhere, with millisecond precision and random bits
+	for _, s := range []string{
+		"01890a5d-ac96-774b-bcce-b302099a8057",
+		"017f22e2-79b0-7cc3-98c4-dc0c0c07398f",
+	} {
+		u, err := ParseUUID(s)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: got error %v", s, err)
+		}
+		if _, err := FromUUID(u); !errors.Is(err, ErrNotConvertible) || !errors.Is(err, ErrInvalid) {
+			t.Fatalf("%s: got %v, want %v", s, err, ErrNotConvertible)
+		}
+	}
+	// version 4
+	u, _ := ParseUUID("f47ac10b-58cc-4372-a567-0e02b2c3d479")
+	if _, err := FromUUID(u); !errors.Is(err, ErrNotConvertible) {
+		t.Fatalf("got %v, want %v", err, ErrNotConvertible)
+	}
+
+	l, err := ParseULID("01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	if err != nil {
+		t.Fatalf("Failed to parse ULID: got error %v", err)
+	}
+	if got := l.String(); got != "01ARZ3NDEKTSV4RRFFQ69G5FAV" {
+		t.Fatalf("got %q, want %q", got, "01ARZ3NDEKTSV4RRFFQ69G5FAV")
+	}
+	if got, want := l.Time(), time.UnixMilli(1469922850259).UTC(); !got.Equal(want) {
+		t.Fatalf("got time %v, want %v", got, want)
+	}
+	if _, err := FromULID(l); !errors.Is(err, ErrNotConvertible) {
+		t.Fatalf("got %v, want %v", err, ErrNotConvertible)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{"", "f47ac10b58cc4372a5670e02b2c3d479", "f47ac10b-58cc-4372-a567-0e02b2c3d47z"} {
+		if _, err := ParseUUID(s); err == nil {
+			t.Fatalf("Expected UUID %q to be rejected", s)
+		}
+	}
+	// the first symbol of a ULID can hold at most 3 bits
+	for _, s := range []string{"", "01ARZ3NDEKTSV4RRFFQ69G5FA", "81ARZ3NDEKTSV4RRFFQ69G5FAV", "01ARZ3NDEKTSV4RRFFQ69G5FA!"} {
+		if _, err := ParseULID(s); err == nil {
+			t.Fatalf("Expected ULID %q to be rejected", s)
+		}
+	}
+	if _, err := ParseULID("7ZZZZZZZZZZZZZZZZZZZZZZZZZ"); err != nil {
+		t.Fatalf("Failed to parse largest ULID: got error %v", err)
+	}
+}
+
+func TestUUIDText(t *testing.T) {
+	want, err := New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	s := want.UUID().String()
+	if _, err := FromString(s); err == nil {
+		t.Fatal("Expected UUID text to be rejected by default")
+	}
+
+	SetUUIDText(true)
+	defer SetUUIDText(false)
+	got, err := FromString(s)
+	if err != nil || got != want {
+		t.Fatalf("FromString(%q) = %v, %v, want %v, nil", s, got, err, want)
+	}
+	account, err := ParseAccountID("acct_" + s)
+	if err != nil || ID(account) != want {
+		t.Fatalf("got %v, %v, want %v, nil", account, err, want)
+	}
+	// the StdEncoding form is still accepted
+	if got, err := FromString(want.String()); err != nil || got != want {
+		t.Fatalf("got %v, %v, want %v, nil", got, err, want)
+	}
+}
@@ -0,0 +1,58 @@
+package id
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrWrongKind is returned when a prefixed ID is missing its prefix or
+// refers to a different kind of entity than the one expected.
+var ErrWrongKind = invalid("wrong kind")
+
+// Kind identifies the type of entity an ID refers to. It is written as a
+// prefix on the ID's string form, separated by an underscore, so that IDs of
+// different kinds cannot be mistaken for one another.
+type Kind string
+
+const (
+	KindUser        Kind = "usr"
+	KindAccount     Kind = "acct"
+	KindTransaction Kind = "txn"
+	KindQuote       Kind = "fxq"
+)
+
+// Prefix returns the string that precedes IDs of kind k, e.g. "acct_"
+func (k Kind) Prefix() string {
+	return string(k) + "_"
+}
+
+// Format returns the StdEncoding string form of id prefixed with k and
+// followed by its check symbol, e.g. "acct_BTECYLA-AAC3RCLSSVM-7". Prefixed
+// IDs are the ones people read back and type in, so they carry the symbol
+// to catch typos.
+func (k Kind) Format(id ID) string {
+	return k.Prefix() + checkEncoding.EncodeToString(id)
+}
+
+// Parse parses a prefixed ID, which must be of kind k. The remainder of the
+// string is parsed by FromString, so the check symbol is optional but
+// verified when present.
+func (k Kind) Parse(s string) (ID, error) {
+	got, rest, err := SplitKind(s)
+	if err != nil {
+		return ID{}, fmt.Errorf("%w: expected %s ID", err, k.Prefix())
+	}
+	if got != k {
+		return ID{}, fmt.Errorf("%w: expected %s ID, got %s ID", ErrWrongKind, k.Prefix(), got.Prefix())
+	}
+	return FromString(rest)
+}
+
+// SplitKind separates a prefixed ID into its kind and unprefixed remainder
+func SplitKind(s string) (Kind, string, error) {
+	i := strings.IndexByte(s, '_')
+	if i < 0 {
+		return "", "", fmt.Errorf("%w: missing prefix", ErrWrongKind)
+	}
+	return Kind(s[:i]), s[i+1:], nil
+}
@@ -0,0 +1,68 @@
+package id
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestKindRoundTrip(t *testing.T) {
+	for _, k := range []Kind{KindUser, KindAccount, KindTransaction, KindQuote} {
+		want, err := New()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: got error %v", err)
+		}
+		s := k.Format(want)
+		if checked := k.Prefix() + StdEncoding.WithCheck().EncodeToString(want); s != checked {
+			t.Fatalf("got %q, want %q", s, checked)
+		}
+		// the check symbol is optional when parsing
+		for _, in := range []string{s, k.Prefix() + want.String()} {
+			got, err := k.Parse(in)
+			if err != nil {
+				t.Fatalf("Failed to parse %q: got error %v", in, err)
+			}
+			if got != want {
+				t.Fatalf("Parse(%q) = %v, want %v", in, got, want)
+			}
+		}
+		if _, err := k.Parse(s[:len(s)-1] + string(nextCheckSymbol(s[len(s)-1]))); !errors.Is(err, ErrCheckSymbol) {
+			t.Fatalf("Parse with wrong check symbol: got %v, want %v", err, ErrCheckSymbol)
+		}
+	}
+}
+
+func TestKindPrefixes(t *testing.T) {
+	for k, want := range map[Kind]string{
+		KindUser:        "usr_",
+		KindAccount:     "acct_",
+		KindTransaction: "txn_",
+		KindQuote:       "fxq_",
+	} {
+		if got := k.Prefix(); got != want {
+			t.Fatalf("got prefix %q, want %q", got, want)
+		}
+	}
+}
+
+func TestKindWrong(t *testing.T) {
+	id, err := New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	for _, s := range []string{
+		KindUser.Format(id),
+		KindTransaction.Format(id),
+		"foo_" + id.String(),
+		id.String(),
+	} {
+		if _, err := KindAccount.Parse(s); !errors.Is(err, ErrWrongKind) {
+			t.Fatalf("Parse(%q): got %v, want %v", s, err, ErrWrongKind)
+		}
+	}
+}
+
+func TestKindInvalidID(t *testing.T) {
+	if _, err := KindAccount.Parse("acct_BC5YUGAAATBBDEIN"); err == nil || errors.Is(err, ErrWrongKind) {
+		t.Fatalf("got %v, want a decoding error", err)
+	}
+}
@@ -0,0 +1,308 @@
+package id
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// StorageFormat selects the representation Value writes to the database
+type StorageFormat int32
+
+const (
+	// StorageText stores the StdEncoding string form, for varchar columns
+	StorageText StorageFormat = iota
+	// StorageBinary stores the 11 or 12 byte form, for bytea columns.
+	// Postgres compares bytea bytewise, so ORDER BY id on a bytea column
+	// follows creation order, which the StdEncoding string form does not.
+	StorageBinary
+)
+
+var storageFormat int32
+
+// SetStorageFormat selects how IDs are written to the database. It should be
+// called once at startup, before any IDs are written. Scan accepts either
+// format regardless of this setting.
+func SetStorageFormat(f StorageFormat) {
+	atomic.StoreInt32(&storageFormat, int32(f))
+}
+
+// GetStorageFormat returns the format selected by SetStorageFormat
+func GetStorageFormat() StorageFormat {
+	return StorageFormat(atomic.LoadInt32(&storageFormat))
+}
+
+// MarshalText implements encoding.TextMarshaler using the StdEncoding form
+func (id ID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting any string
+// FromString accepts
+func (id *ID) UnmarshalText(b []byte) error {
+	parsed, err := FromString(string(b))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding id as a JSON string
+func (id ID) MarshalJSON() ([]byte, error) {
+	return marshalJSON(id.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (id *ID) UnmarshalJSON(b []byte) error {
+	return unmarshalJSON(b, id.UnmarshalText)
+}
+
+// Value implements driver.Valuer, storing id in the format selected by
+// SetStorageFormat
+func (id ID) Value() (driver.Value, error) {
+	if GetStorageFormat() == StorageBinary {
+		return id.Bytes(), nil
+	}
+	return id.String(), nil
+}
+
+// Scan implements sql.Scanner for columns holding either the string or the
+// byte form of an ID. The two cannot be confused: the byte form is 11 or 12
+// bytes long, and the string form at least 17 characters.
+func (id *ID) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case string:
+		return id.UnmarshalText([]byte(v))
+	case []byte:
+		if len(v) == v1Len || len(v) == v2Len {
+			parsed, err := FromBytes(v)
+			if err != nil {
+				return err
+			}
+			*id = parsed
+			return nil
+		}
+		return id.UnmarshalText(v)
+	case nil:
+		return fmt.Errorf("id: cannot scan NULL into %T", id)
+	}
+	return fmt.Errorf("id: cannot scan %T into %T", src, id)
+}
+
+func marshalJSON(s string) ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// unmarshalJSON decodes a JSON string with unmarshalText. Like the standard
+// library's decoders it treats null as a no-op.
+func unmarshalJSON(b []byte, unmarshalText func([]byte) error) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.New("Invalid ID: must be a JSON string")
+	}
+	return unmarshalText([]byte(s))
+}
+
+// UserID, AccountID, TransactionID and QuoteID are IDs of a known Kind. Their text
+// and JSON forms carry the kind's prefix, and decoding them rejects IDs of
+// any other kind. In the database they are stored unprefixed, like ID.
+type (
+	UserID        ID
+	AccountID     ID
+	TransactionID ID
+	QuoteID       ID
+)
+
+// ParseUserID parses a "usr_" prefixed ID
+func ParseUserID(s string) (UserID, error) {
+	parsed, err := KindUser.Parse(s)
+	return UserID(parsed), err
+}
+
+// ParseAccountID parses an "acct_" prefixed ID
+func ParseAccountID(s string) (AccountID, error) {
+	parsed, err := KindAccount.Parse(s)
+	return AccountID(parsed), err
+}
+
+// ParseTransactionID parses a "txn_" prefixed ID
+func ParseTransactionID(s string) (TransactionID, error) {
+	parsed, err := KindTransaction.Parse(s)
+	return TransactionID(parsed), err
+}
+
+// ParseQuoteID parses an "fxq_" prefixed ID
+func ParseQuoteID(s string) (QuoteID, error) {
+	parsed, err := KindQuote.Parse(s)
+	return QuoteID(parsed), err
+}
+
+// String returns the prefixed StdEncoding form of u, with its check symbol
+func (u UserID) String() string {
+	return KindUser.Format(ID(u))
+}
+
+// MarshalText implements encoding.TextMarshaler using the prefixed form
+func (u UserID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, rejecting IDs of any
+// other kind
+func (u *UserID) UnmarshalText(b []byte) error {
+	parsed, err := ParseUserID(string(b))
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (u UserID) MarshalJSON() ([]byte, error) {
+	return marshalJSON(u.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (u *UserID) UnmarshalJSON(b []byte) error {
+	return unmarshalJSON(b, u.UnmarshalText)
+}
+
+// Value implements driver.Valuer, storing u unprefixed
+func (u UserID) Value() (driver.Value, error) {
+	return ID(u).Value()
+}
+
+// Scan implements sql.Scanner
+func (u *UserID) Scan(src interface{}) error {
+	return (*ID)(u).Scan(src)
+}
+
+// String returns the prefixed StdEncoding form of a, with its check symbol
+func (a AccountID) String() string {
+	return KindAccount.Format(ID(a))
+}
+
+// MarshalText implements encoding.TextMarshaler using the prefixed form
+func (a AccountID) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, rejecting IDs of any
+// other kind
+func (a *AccountID) UnmarshalText(b []byte) error {
+	parsed, err := ParseAccountID(string(b))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (a AccountID) MarshalJSON() ([]byte, error) {
+	return marshalJSON(a.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (a *AccountID) UnmarshalJSON(b []byte) error {
+	return unmarshalJSON(b, a.UnmarshalText)
+}
+
+// Value implements driver.Valuer, storing a unprefixed
+func (a AccountID) Value() (driver.Value, error) {
+	return ID(a).Value()
+}
+
+// Scan implements sql.Scanner
+func (a *AccountID) Scan(src interface{}) error {
+	return (*ID)(a).Scan(src)
+}
+
+// String returns the prefixed StdEncoding form of t, with its check symbol
+func (t TransactionID) String() string {
+	return KindTransaction.Format(ID(t))
+}
+
+// MarshalText implements encoding.TextMarshaler using the prefixed form
+func (t TransactionID) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, rejecting IDs of any
+// other kind
+func (t *TransactionID) UnmarshalText(b []byte) error {
+	parsed, err := ParseTransactionID(string(b))
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (t TransactionID) MarshalJSON() ([]byte, error) {
+	return marshalJSON(t.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *TransactionID) UnmarshalJSON(b []byte) error {
+	return unmarshalJSON(b, t.UnmarshalText)
+}
+
+// Value implements driver.Valuer, storing t unprefixed
+func (t TransactionID) Value() (driver.Value, error) {
+	return ID(t).Value()
+}
+
+// Scan implements sql.Scanner
+func (t *TransactionID) Scan(src interface{}) error {
+	return (*ID)(t).Scan(src)
+}
+
+// String returns the prefixed StdEncoding form of q, with its check symbol
+func (q QuoteID) String() string {
+	return KindQuote.Format(ID(q))
+}
+
+// MarshalText implements encoding.TextMarshaler using the prefixed form
+func (q QuoteID) MarshalText() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, rejecting IDs of any
+// other kind
+func (q *QuoteID) UnmarshalText(b []byte) error {
+	parsed, err := ParseQuoteID(string(b))
+	if err != nil {
+		return err
+	}
+	*q = parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (q QuoteID) MarshalJSON() ([]byte, error) {
+	return marshalJSON(q.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (q *QuoteID) UnmarshalJSON(b []byte) error {
+	return unmarshalJSON(b, q.UnmarshalText)
+}
+
+// Value implements driver.Valuer, storing q unprefixed
+func (q QuoteID) Value() (driver.Value, error) {
+	return ID(q).Value()
+}
+
+// Scan implements sql.Scanner
+func (q *QuoteID) Scan(src interface{}) error {
+	return (*ID)(q).Scan(src)
+}
@@ -0,0 +1,187 @@
+package id
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+var (
+	_ sql.Scanner              = (*ID)(nil)
+	_ driver.Valuer            = ID{}
+	_ encoding.TextMarshaler   = ID{}
+	_ encoding.TextUnmarshaler = (*ID)(nil)
+	_ json.Marshaler           = ID{}
+	_ json.Unmarshaler         = (*ID)(nil)
+	_ sql.Scanner              = (*AccountID)(nil)
+	_ driver.Valuer            = AccountID{}
+	_ json.Unmarshaler         = (*AccountID)(nil)
+	_ sql.Scanner              = (*QuoteID)(nil)
+	_ driver.Valuer            = QuoteID{}
+	_ json.Unmarshaler         = (*QuoteID)(nil)
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	want, err := New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	b, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("Failed to marshal ID: got error %v", err)
+	}
+	if string(b) != `"`+want.String()+`"` {
+		t.Fatalf("got %s, want %q", b, want.String())
+	}
+	var got ID
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Failed to unmarshal ID: got error %v", err)
+	}
+	if got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestJSONInvalid(t *testing.T) {
+	var id ID
+	for _, s := range []string{`"BC5YUGAAATBBDEIN"`, `42`, `{}`} {
+		if err := json.Unmarshal([]byte(s), &id); err == nil {
+			t.Fatalf("Expected %s to be rejected", s)
+		}
+	}
+}
+
+func TestTypedJSON(t *testing.T) {
+	raw, err := New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	type response struct {
+		Account  AccountID      `json:"accountId"`
+		External *AccountID     `json:"externalAccount,omitempty"`
+		Related  *TransactionID `json:"relatedTransactionId,omitempty"`
+	}
+	b, err := json.Marshal(response{Account: AccountID(raw)})
+	if err != nil {
+		t.Fatalf("Failed to marshal: got error %v", err)
+	}
+	if want := `{"accountId":"acct_` + StdEncoding.WithCheck().EncodeToString(raw) + `"}`; string(b) != want {
+		t.Fatalf("got %s, want %s", b, want)
+	}
+
+	var got response
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Failed to unmarshal: got error %v", err)
+	}
+	if ID(got.Account) != raw || got.External != nil {
+		t.Fatalf("got %+v, want account %v", got, raw)
+	}
+
+	wrong := []byte(`{"accountId":"usr_` + raw.String() + `"}`)
+	if err := json.Unmarshal(wrong, &got); !errors.Is(err, ErrWrongKind) {
+		t.Fatalf("got %v, want %v", err, ErrWrongKind)
+	}
+	bare := []byte(`{"accountId":"` + raw.String() + `"}`)
+	if err := json.Unmarshal(bare, &got); !errors.Is(err, ErrWrongKind) {
+		t.Fatalf("got %v, want %v", err, ErrWrongKind)
+	}
+}
+
+func TestSQL(t *testing.T) {
+	want, err := New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	v, err := want.Value()
+	if err != nil {
+		t.Fatalf("Failed to get value: got error %v", err)
+	}
+	if v != want.String() {
+		t.Fatalf("got %v, want %v", v, want.String())
+	}
+	// typed IDs are stored without their prefix
+	if tv, _ := TransactionID(want).Value(); tv != v {
+		t.Fatalf("got %v, want %v", tv, v)
+	}
+
+	for _, src := range []interface{}{want.String(), []byte(want.String())} {
+		var got ID
+		if err := got.Scan(src); err != nil {
+			t.Fatalf("Failed to scan %T: got error %v", src, err)
+		}
+		if got != want {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		var typed AccountID
+		if err := typed.Scan(src); err != nil {
+			t.Fatalf("Failed to scan %T: got error %v", src, err)
+		}
+		if ID(typed) != want {
+			t.Fatalf("got %v, want %v", typed, want)
+		}
+	}
+
+	var got ID
+	for _, src := range []interface{}{nil, 42, "BC5YUGAAATBBDEIN"} {
+		if err := got.Scan(src); err == nil {
+			t.Fatalf("Expected scanning %v to fail", src)
+		}
+	}
+}
+
+func TestSQLBinary(t *testing.T) {
+	SetStorageFormat(StorageBinary)
+	defer SetStorageFormat(StorageText)
+
+	for _, v := range []Version{V1, V2} {
+		g := newTestGenerator(t, WithVersion(v))
+		want, err := g.New()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: got error %v", err)
+		}
+		val, err := AccountID(want).Value()
+		if err != nil {
+			t.Fatalf("Failed to get value: got error %v", err)
+		}
+		b, ok := val.([]byte)
+		if !ok || !bytes.Equal(b, want.Bytes()) {
+			t.Fatalf("got %v, want %x", val, want.Bytes())
+		}
+		var got ID
+		if err := got.Scan(b); err != nil {
+			t.Fatalf("Failed to scan bytes: got error %v", err)
+		}
+		if got != want {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		// text written before a migration still scans
+		if err := got.Scan([]byte(want.String())); err != nil || got != want {
+			t.Fatalf("got %v, %v, want %v, nil", got, err, want)
+		}
+	}
+
+	// a 12 byte value without the version bit is not an ID
+	var got ID
+	if err := got.Scan(make([]byte, 12)); err == nil {
+		t.Fatal("Expected scanning 12 zero bytes to fail")
+	}
+}
+
+func TestBinaryOrder(t *testing.T) {
+	g := newTestGenerator(t)
+	var last []byte
+	for i := 0; i < 1000; i++ {
+		next, err := g.New()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: got error %v", err)
+		}
+		if bytes.Compare(last, next.Bytes()) >= 0 {
+			t.Fatalf("Next ID not monotonic: (last, new) = (%x, %x)", last, next.Bytes())
+		}
+		last = next.Bytes()
+	}
+}
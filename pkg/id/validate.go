@@ -0,0 +1,187 @@
+package id
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalid matches, with errors.Is, every error reporting a malformed or
+// implausible ID: each of the sentinels below, ErrCheckSymbol, ErrWrongKind,
+// and those of UUID and ULID conversion such as ErrNotConvertible.
+var ErrInvalid = errors.New("Invalid ID")
+
+var (
+	// ErrLength is returned for strings with the wrong number of characters
+	ErrLength = invalid("wrong length")
+	// ErrSymbol is returned for strings containing a character outside the
+	// encoding's alphabet
+	ErrSymbol = invalid("invalid character")
+	// ErrDash is returned by strict parsing when the string has no dash
+	// after its seventh character
+	ErrDash = invalid("dash must follow the seventh character")
+	// ErrPadding is returned when the unused low bits of the final character
+	// are set, which would give one ID several string forms
+	ErrPadding = invalid("padding bits must be zero")
+	// ErrNonCanonical is returned by strict parsing for strings that decode
+	// to an ID but differ from its String form, e.g. in case
+	ErrNonCanonical = invalid("not in canonical form")
+	// ErrZero is returned for the zero ID, which no Generator issues
+	ErrZero = invalid("uninitialized")
+	// ErrFuture is returned for IDs timestamped further in the future than
+	// the Validator tolerates
+	ErrFuture = invalid("timestamp is in the future")
+	// ErrTooEarly is returned for IDs timestamped before the Validator's
+	// NotBefore
+	ErrTooEarly = invalid("timestamp is before the earliest allowed time")
+)
+
+// validationError is the type of the validation sentinels. Each one matches
+// ErrInvalid as well as itself.
+type validationError struct {
+	msg string
+}
+
+func invalid(msg string) error {
+	return &validationError{msg: msg}
+}
+
+func (e *validationError) Error() string {
+	return "Invalid ID: " + e.msg
+}
+
+func (e *validationError) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// A Validator checks that IDs are well formed and were plausibly issued by
+// a Generator. The zero Validator checks IDs counted from DefaultEpoch
+// against the system clock, tolerating no timestamps in the future.
+type Validator struct {
+	// Epoch is the epoch IDs are counted from. The zero value means
+	// DefaultEpoch.
+	Epoch time.Time
+	// NotBefore rejects IDs timestamped before it. An ID's timestamp cannot
+	// encode a time before its epoch, so this only needs to be set to
+	// tighten the bound, e.g. to when a deployment first issued IDs.
+	NotBefore time.Time
+	// FutureTolerance is how far past the current second an ID's timestamp
+	// may be, to allow for skew between replicas' clocks and for seconds
+	// borrowed by OverflowBorrow.
+	FutureTolerance time.Duration
+	// Clock is used to read the current time. The zero value means the
+	// system clock.
+	Clock Clock
+}
+
+// Validate reports whether id could have been issued by now, assuming it
+// was minted from DefaultEpoch
+func Validate(id ID) (bool, error) {
+	var v Validator
+	if err := v.Validate(id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ParseStrict parses the canonical StdEncoding form of an ID, exactly as
+// String writes it, and validates it with the zero Validator
+func ParseStrict(s string) (ID, error) {
+	var v Validator
+	return v.Parse(s)
+}
+
+// Validate checks that id is not the zero ID, has a valid version marker and
+// padding, and is timestamped within [NotBefore, now+FutureTolerance].
+// Errors match ErrInvalid and the specific sentinel with errors.Is.
+func (v *Validator) Validate(id ID) error {
+	if id == (ID{}) {
+		return ErrZero
+	}
+	if id.Version() == V1 && id[v2Len-1] != 0 {
+		return fmt.Errorf("%w: V1 IDs are 11 bytes", ErrPadding)
+	}
+	t := id.TimeFrom(v.epoch())
+	if !v.NotBefore.IsZero() && t.Before(v.NotBefore.Truncate(time.Second)) {
+		return fmt.Errorf("%w: %v is before %v", ErrTooEarly, t, v.NotBefore.UTC())
+	}
+	if limit := v.now().Add(v.FutureTolerance); t.After(limit) {
+		return fmt.Errorf("%w: %v is after %v", ErrFuture, t, limit.UTC())
+	}
+	return nil
+}
+
+// Parse parses s strictly and validates the result. s must be the canonical
+// form String writes: upper case, with the dash after the seventh character
+// and zero padding bits, optionally followed by a dash and the ID's check
+// symbol, which must match. After SetUUIDText(true), the lower case text
+// form of a UUID produced by ID.UUID is also accepted.
+func (v *Validator) Parse(s string) (ID, error) {
+	id, err := parseCanonical(s)
+	if err != nil {
+		return id, err
+	}
+	return id, v.Validate(id)
+}
+
+// ParseKind parses a prefixed ID of kind k strictly and validates it
+func (v *Validator) ParseKind(k Kind, s string) (ID, error) {
+	got, rest, err := SplitKind(s)
+	if err != nil {
+		return ID{}, fmt.Errorf("%w: expected %s ID", err, k.Prefix())
+	}
+	if got != k {
+		return ID{}, fmt.Errorf("%w: expected %s ID, got %s ID", ErrWrongKind, k.Prefix(), got.Prefix())
+	}
+	return v.Parse(rest)
+}
+
+func (v *Validator) epoch() time.Time {
+	if v.Epoch.IsZero() {
+		return DefaultEpoch
+	}
+	return v.Epoch
+}
+
+func (v *Validator) now() time.Time {
+	if v.Clock == nil {
+		return time.Now()
+	}
+	return v.Clock.Now()
+}
+
+// checkEncoding is StdEncoding with check symbols
+var checkEncoding = StdEncoding.WithCheck()
+
+// parseCanonical decodes s with FromString and rejects it unless it is the
+// string the decoded ID encodes back to, with or without a check symbol
+func parseCanonical(s string) (ID, error) {
+	if GetUUIDText() && isUUIDText(s) {
+		id, err := FromString(s)
+		if err == nil && id.UUID().String() != s {
+			return id, fmt.Errorf("%w: UUIDs must be lower case", ErrNonCanonical)
+		}
+		return id, err
+	}
+	canonical := ID.String
+	switch len(s) {
+	case v1Symbols + 1, v2Symbols + 1:
+	case v1Symbols + 3, v2Symbols + 3:
+		canonical = checkEncoding.EncodeToString
+	default:
+		return ID{}, fmt.Errorf("%w: must be %d or %d characters, or %d or %d with a check symbol",
+			ErrLength, v1Symbols+1, v2Symbols+1, v1Symbols+3, v2Symbols+3)
+	}
+	if s[7] != '-' {
+		return ID{}, ErrDash
+	}
+	// FromString verifies the check symbol, if there is one
+	id, err := FromString(s)
+	if err != nil {
+		return id, err
+	}
+	if canonical(id) != s {
+		return id, ErrNonCanonical
+	}
+	return id, nil
+}
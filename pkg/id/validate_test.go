@@ -0,0 +1,205 @@
+package id
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseStrict(t *testing.T) {
+	for _, v := range []Version{V1, V2} {
+		want, err := newTestGenerator(t, WithVersion(v)).New()
+		if err != nil {
+			t.Fatalf("Failed to generate ID: got error %v", err)
+		}
+		s := want.String()
+		// without its dash a V2 string is as long as a V1 string
+		noDash := ErrLength
+		if v == V2 {
+			noDash = ErrDash
+		}
+		if got, err := ParseStrict(s); err != nil || got != want {
+			t.Fatalf("ParseStrict(%q) = %v, %v, want %v, nil", s, got, err, want)
+		}
+		checked := checkEncoding.EncodeToString(want)
+		if got, err := ParseStrict(checked); err != nil || got != want {
+			t.Fatalf("ParseStrict(%q) = %v, %v, want %v, nil", checked, got, err, want)
+		}
+		// a mistyped character is caught by the check symbol
+		typo := checked[:3] + string(nextSymbol(checked[3])) + checked[4:]
+		wrongCheck := checked[:len(checked)-1] + string(nextCheckSymbol(checked[len(checked)-1]))
+
+		for _, tc := range []struct {
+			in   string
+			want error
+		}{
+			{strings.ToLower(s), ErrNonCanonical},
+			{strings.Replace(s, "-", "", 1), noDash},
+			{s[:6] + "-" + s[6:7] + s[8:], ErrDash},
+			{s[:len(s)-2], ErrLength},
+			{s + "AA", ErrLength},
+			{s[:len(s)-1] + "1", ErrSymbol},
+			{"", ErrLength},
+			{typo, ErrCheckSymbol},
+			{wrongCheck, ErrCheckSymbol},
+			{strings.Replace(checked, "-", "", 1), ErrDash},
+			{checked[:len(checked)-2] + "=" + checked[len(checked)-1:], ErrLength},
+		} {
+			_, err := ParseStrict(tc.in)
+			if !errors.Is(err, tc.want) || !errors.Is(err, ErrInvalid) {
+				t.Fatalf("ParseStrict(%q): got %v, want %v", tc.in, err, tc.want)
+			}
+		}
+	}
+	// the legacy 17 character form is not canonical
+	if _, err := ParseStrict("BC5YUGA-AATBBDEIN"); !errors.Is(err, ErrLength) {
+		t.Fatalf("got %v, want %v", err, ErrLength)
+	}
+}
+
+// nextSymbol returns the StdEncoding symbol after c, wrapping around
+func nextSymbol(c byte) byte {
+	i := strings.IndexByte(stdAlphabet, c)
+	return stdAlphabet[(i+1)%len(stdAlphabet)]
+}
+
+// nextCheckSymbol returns the check symbol after c, wrapping around
+func nextCheckSymbol(c byte) byte {
+	return checkAlphabet[(checkDecodeMap[c]+1)%byte(len(checkAlphabet))]
+}
+
+func TestPadding(t *testing.T) {
+	// "A" and "B" differ only in the two padding bits of a V1 ID
+	if _, err := FromString("BC5YUGA-AATBBDEINAA"); err != nil {
+		t.Fatalf("Failed to parse string: got error %v", err)
+	}
+	for _, s := range []string{"BC5YUGA-AATBBDEINAB", "BC5YUGAAATBBDEINAD"} {
+		if _, err := FromString(s); !errors.Is(err, ErrPadding) {
+			t.Fatalf("FromString(%q): got %v, want %v", s, err, ErrPadding)
+		}
+	}
+	// a V1 ID with a set twelfth byte has no string form to pad
+	id, _ := FromString("BC5YUGA-AATBBDEINAA")
+	id[v2Len-1] = 0x02
+	if _, err := Validate(id); !errors.Is(err, ErrPadding) {
+		t.Fatalf("got %v, want %v", err, ErrPadding)
+	}
+}
+
+func TestValidator(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	clk := newFakeClock(now)
+	g := newClockedGenerator(t, clk)
+	issued, err := g.New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	v := Validator{Clock: clk}
+	if err := v.Validate(issued); err != nil {
+		t.Fatalf("Expected ID to be valid: got error %v", err)
+	}
+	if err := v.Validate(ID{}); !errors.Is(err, ErrZero) || !errors.Is(err, ErrInvalid) {
+		t.Fatalf("got %v, want %v", err, ErrZero)
+	}
+
+	// a replica 3 seconds ahead
+	clk.Advance(3 * time.Second)
+	ahead, err := g.New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	clk.Advance(-3 * time.Second)
+	if err := v.Validate(ahead); !errors.Is(err, ErrFuture) {
+		t.Fatalf("got %v, want %v", err, ErrFuture)
+	}
+	v.FutureTolerance = 3 * time.Second
+	if err := v.Validate(ahead); err != nil {
+		t.Fatalf("Expected ID within tolerance to be valid: got error %v", err)
+	}
+
+	v.NotBefore = now.Add(time.Second)
+	if err := v.Validate(issued); !errors.Is(err, ErrTooEarly) {
+		t.Fatalf("got %v, want %v", err, ErrTooEarly)
+	}
+	// the bound is inclusive of the second containing it
+	v.NotBefore = now.Add(500 * time.Millisecond)
+	if err := v.Validate(issued); err != nil {
+		t.Fatalf("Expected ID to be valid: got error %v", err)
+	}
+
+	// the same bits are an hour and a bit later from the legacy epoch
+	legacy := Validator{Clock: clk, Epoch: LegacyEpoch}
+	if err := legacy.Validate(issued); !errors.Is(err, ErrFuture) {
+		t.Fatalf("got %v, want %v", err, ErrFuture)
+	}
+}
+
+func TestValidatorParseKind(t *testing.T) {
+	want, err := New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	var v Validator
+	got, err := v.ParseKind(KindAccount, KindAccount.Format(want))
+	if err != nil || got != want {
+		t.Fatalf("got %v, %v, want %v, nil", got, err, want)
+	}
+	for _, tc := range []struct {
+		in   string
+		want error
+	}{
+		{KindUser.Format(want), ErrWrongKind},
+		{want.String(), ErrWrongKind},
+		{"acct_" + strings.ToLower(want.String()), ErrNonCanonical},
+		{"acct_" + ID{}.String(), ErrZero},
+	} {
+		_, err := v.ParseKind(KindAccount, tc.in)
+		if !errors.Is(err, tc.want) || !errors.Is(err, ErrInvalid) {
+			t.Fatalf("ParseKind(%q): got %v, want %v", tc.in, err, tc.want)
+		}
+	}
+
+	SetUUIDText(true)
+	defer SetUUIDText(false)
+	u := want.UUID().String()
+	if got, err := v.ParseKind(KindAccount, "acct_"+u); err != nil || got != want {
+		t.Fatalf("got %v, %v, want %v, nil", got, err, want)
+	}
+	if _, err := v.ParseKind(KindAccount, "acct_"+strings.ToUpper(u)); !errors.Is(err, ErrNonCanonical) {
+		t.Fatalf("got %v, want %v", err, ErrNonCanonical)
+	}
+}
+
+func TestParseStrictUUIDText(t *testing.T) {
+	want, err := New()
+	if err != nil {
+		t.Fatalf("Failed to generate ID: got error %v", err)
+	}
+	SetUUIDText(true)
+	defer SetUUIDText(false)
+	u := want.UUID().String()
+	if got, err := ParseStrict(u); err != nil || got != want {
+		t.Fatalf("ParseStrict(%q) = %v, %v, want %v, nil", u, got, err, want)
+	}
+	for _, tc := range []struct {
+		in   string
+		want error
+	}{
+		{u[:len(u)-1] + "g", ErrUUIDSyntax},
+		{u[:14] + "4" + u[15:], ErrNotConvertible},
+		{strings.ToUpper(u), ErrNonCanonical},
+		{strings.ToLower(want.String()), ErrNonCanonical},
+		{want.String() + "AA", ErrLength},
+		{"", ErrLength},
+	} {
+		_, err := ParseStrict(tc.in)
+		if !errors.Is(err, tc.want) || !errors.Is(err, ErrInvalid) {
+			t.Fatalf("ParseStrict(%q): got %v, want %v", tc.in, err, tc.want)
+		}
+		_, err = FromString(tc.in)
+		if err != nil && !errors.Is(err, ErrInvalid) {
+			t.Fatalf("FromString(%q): error %v does not match ErrInvalid", tc.in, err)
+		}
+	}
+}
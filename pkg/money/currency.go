@@ -0,0 +1,101 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrCurrency is returned for codes that are not active ISO 4217 currencies
+var ErrCurrency = errors.New("Invalid currency: not an ISO 4217 code")
+
+// Currency is an ISO 4217 alphabetic currency code such as "USD". The zero
+// value is not a valid currency.
+type Currency string
+
+// minorUnits holds the number of decimal places of each active ISO 4217
+// currency. No currency has more than Scale.
+var minorUnits = map[Currency]int{}
+
+func init() {
+	for units, codes := range map[int]string{
+		0: "BIF CLP DJF GNF ISK JPY KMF KRW PYG RWF UGX UYI VND VUV XAF XOF XPF",
+		2: "AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BMD BND BOB " +
+			"BRL BSD BTN BWP BYN BZD CAD CDF CHF CNY COP CRC CUP CVE CZK DKK DOP " +
+			"DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GTQ GYD HKD HNL HTG " +
+			"HUF IDR ILS INR IRR JMD KES KGS KHR KPW KYD KZT LAK LBP LKR LRD LSL " +
+			"MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MYR MZN NAD NGN NIO " +
+			"NOK NPR NZD PAB PEN PGK PHP PKR PLN QAR RON RSD RUB SAR SBD SCR SDG " +
+			"SEK SGD SHP SLE SOS SRD SSP STN SVC SYP SZL THB TJS TMT TOP TRY TTD " +
+			"TWD TZS UAH USD UYU UZS VES WST XCD YER ZAR ZMW ZWL",
+		3: "BHD IQD JOD KWD LYD OMR TND",
+		4: "CLF UYW",
+	} {
+		for _, code := range strings.Fields(codes) {
+			minorUnits[Currency(code)] = units
+		}
+	}
+}
+
+// ParseCurrency parses an ISO 4217 code in either case
+func ParseCurrency(s string) (Currency, error) {
+	c := Currency(strings.ToUpper(s))
+	if _, ok := minorUnits[c]; !ok || len(s) != 3 {
+		return "", fmt.Errorf("%w: %q", ErrCurrency, s)
+	}
+	return c, nil
+}
+
+// MinorUnits returns the number of decimal places amounts in c may have,
+// e.g. 2 for USD and 0 for JPY
+func (c Currency) MinorUnits() int {
+	return minorUnits[c]
+}
+
+// Check returns ErrPrecision if a has more decimal places than c allows
+func (c Currency) Check(a Amount) error {
+	if a.Units()%pow10(Scale-c.MinorUnits()) != 0 {
+		return fmt.Errorf("%w: %s amounts have at most %d decimal places", ErrPrecision, c, c.MinorUnits())
+	}
+	return nil
+}
+
+// String returns the code of c
+func (c Currency) String() string {
+	return string(c)
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (c Currency) MarshalText() ([]byte, error) {
+	return []byte(c), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting any code
+// ParseCurrency accepts. It also decodes JSON strings.
+func (c *Currency) UnmarshalText(b []byte) error {
+	parsed, err := ParseCurrency(string(b))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// Value implements driver.Valuer
+func (c Currency) Value() (driver.Value, error) {
+	return string(c), nil
+}
+
+// Scan implements sql.Scanner for text columns
+func (c *Currency) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return c.UnmarshalText(v)
+	case string:
+		return c.UnmarshalText([]byte(v))
+	case nil:
+		return fmt.Errorf("money: cannot scan NULL into %T", c)
+	}
+	return fmt.Errorf("money: cannot scan %T into %T", src, c)
+}
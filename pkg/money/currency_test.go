@@ -0,0 +1,88 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseCurrency(t *testing.T) {
+	for in, want := range map[string]Currency{"USD": "USD", "jpy": "JPY", "Kwd": "KWD"} {
+		got, err := ParseCurrency(in)
+		if err != nil {
+			t.Fatalf("ParseCurrency(%q): got error %v", in, err)
+		}
+		if got != want {
+			t.Fatalf("ParseCurrency(%q) = %v, want %v", in, got, want)
+		}
+	}
+	for _, in := range []string{"", "US", "USDX", "XXX", "ABC", "usd "} {
+		if _, err := ParseCurrency(in); !errors.Is(err, ErrCurrency) {
+			t.Fatalf("ParseCurrency(%q): got error %v, want ErrCurrency", in, err)
+		}
+	}
+}
+
+func TestMinorUnits(t *testing.T) {
+	for c, want := range map[Currency]int{"USD": 2, "EUR": 2, "JPY": 0, "KWD": 3, "CLF": 4} {
+		if got := c.MinorUnits(); got != want {
+			t.Fatalf("%v.MinorUnits() = %d, want %d", c, got, want)
+		}
+	}
+	for c, units := range minorUnits {
+		if units > Scale {
+			t.Fatalf("%v has %d minor units, more than Amount holds", c, units)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		currency Currency
+		amount   string
+		ok       bool
+	}{
+		{"USD", "12.34", true},
+		{"USD", "12.345", false},
+		{"USD", "-0.01", true},
+		{"JPY", "100", true},
+		{"JPY", "100.5", false},
+		{"KWD", "1.234", true},
+		{"KWD", "1.2345", false},
+		{"CLF", "1.2345", true},
+	}
+	for _, tt := range tests {
+		err := tt.currency.Check(MustParse(tt.amount))
+		if tt.ok && err != nil {
+			t.Fatalf("%v.Check(%s): got error %v", tt.currency, tt.amount, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrPrecision) {
+			t.Fatalf("%v.Check(%s): got error %v, want ErrPrecision", tt.currency, tt.amount, err)
+		}
+	}
+}
+
+func TestCurrencyJSON(t *testing.T) {
+	type account struct {
+		Currency Currency `json:"currency"`
+	}
+	var a account
+	if err := json.Unmarshal([]byte(`{"currency": "eur"}`), &a); err != nil {
+		t.Fatalf("Unmarshal: got error %v", err)
+	}
+	if a.Currency != "EUR" {
+		t.Fatalf("got %v, want EUR", a.Currency)
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		t.Fatalf("Marshal: got error %v", err)
+	}
+	if string(b) != `{"currency":"EUR"}` {
+		t.Fatalf("Marshal = %s", b)
+	}
+	for _, in := range []string{`{"currency": "EURO"}`, `{"currency": 978}`} {
+		if err := json.Unmarshal([]byte(in), &a); err == nil {
+			t.Fatalf("Expected %s to be rejected", in)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Scale is the number of decimal places an Amount holds, matching the
+	// decimal(15,4) columns it is stored in
+	Scale = 4
+	// Precision is the number of significant digits an Amount holds
+	Precision = 15
+
+	// MaxAmount and MinAmount are the largest and smallest Amounts,
+	// ±99,999,999,999.9999
+	MaxAmount Amount = 999_999_999_999_999
+	MinAmount Amount = -MaxAmount
+
+	// unit is the number of units in 1
+	unit = 10_000
+)
+
+var (
+	// ErrSyntax is returned for strings that are not decimal numbers
+	ErrSyntax = errors.New("Invalid amount: not a decimal number")
+	// ErrPrecision is returned for values with non-zero digits beyond the
+	// fourth decimal place
+	ErrPrecision = errors.New("Invalid amount: more than 4 decimal places")
+	// ErrRange is returned for values, and the results of arithmetic,
+	// outside [MinAmount, MaxAmount]
+	ErrRange = errors.New("Invalid amount: out of range")
+)
+
+// Amount is an exact decimal amount of money with 4 decimal places, held as
+// a count of ten-thousandths. The zero value is 0.
+//
+// Amounts decode from JSON numbers or strings and encode as JSON numbers,
+// and are stored as their decimal text, so they pass through JSON and
+// Postgres numeric columns without the rounding of float64.
+type Amount int64
+
+// FromUnits returns the Amount of n ten-thousandths
+func FromUnits(n int64) (Amount, error) {
+	a := Amount(n)
+	if a < MinAmount || a > MaxAmount {
+		return 0, fmt.Errorf("%w: %d units", ErrRange, n)
+	}
+	return a, nil
+}
+
+// Parse parses a decimal number such as "12.34", "-0.5" or "1e3", in any
+// form a JSON number can take. Trailing zeros beyond the fourth decimal
+// place are allowed; any other digit there is an ErrPrecision.
+func Parse(s string) (Amount, error) {
+	n, err := parseDecimal(s, Scale, Precision)
+	return Amount(n), err
+}
+
+// parseDecimal parses s as a fixed-point number with the given number of
+// decimal places and significant digits, returning it as a count of its
+// smallest unit
+func parseDecimal(s string, scale, precision int) (int64, error) {
+	rest := s
+	neg := false
+	if rest != "" && (rest[0] == '-' || rest[0] == '+') {
+		neg = rest[0] == '-'
+		rest = rest[1:]
+	}
+	exp := 0
+	if i := strings.IndexAny(rest, "eE"); i >= 0 {
+		// an exponent out of int range is clamped to it, which the bound
+		// below treats the same
+		e, err := strconv.Atoi(strings.TrimPrefix(rest[i+1:], "+"))
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+		}
+		exp, rest = e, rest[:i]
+	}
+	whole, frac := rest, ""
+	if i := strings.IndexByte(rest, '.'); i >= 0 {
+		whole, frac = rest[:i], rest[i+1:]
+	}
+	digits := whole + frac
+	if digits == "" {
+		return 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+		}
+	}
+
+	digits = strings.TrimLeft(digits, "0")
+	// Beyond this bound any non-zero value is out of range or below the
+	// smallest unit, and shift below could overflow
+	if limit := precision + scale + len(whole) + len(frac); exp > limit || exp < -limit {
+		switch {
+		case digits == "":
+			return 0, nil
+		case exp > 0:
+			return 0, fmt.Errorf("%w: %q", ErrRange, s)
+		default:
+			return 0, fmt.Errorf("%w: %q", ErrPrecision, s)
+		}
+	}
+
+	// the value is digits×10^shift units
+	shift := exp - len(frac) + scale
+	if shift < 0 {
+		cut := len(digits) + shift
+		if cut < 0 {
+			cut = 0
+		}
+		if strings.Trim(digits[cut:], "0") != "" {
+			return 0, fmt.Errorf("%w: %q", ErrPrecision, s)
+		}
+		digits = digits[:cut]
+	} else if digits != "" {
+		if len(digits)+shift > precision {
+			return 0, fmt.Errorf("%w: %q", ErrRange, s)
+		}
+		digits += strings.Repeat("0", shift)
+	}
+	if digits == "" {
+		return 0, nil
+	}
+	// at most precision digits, so within range
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+	if neg {
+		n = -n
+	}
+	return n, nil
+}
+
+// MustParse is like Parse but panics on error. It is intended for constants
+// in tests and configuration.
+func MustParse(s string) Amount {
+	a, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Units returns a as a count of ten-thousandths
+func (a Amount) Units() int64 {
+	return int64(a)
+}
+
+// Add returns a+b, or ErrRange if the sum is out of range
+func (a Amount) Add(b Amount) (Amount, error) {
+	// both are within ±10^15, so the sum cannot overflow int64
+	return checked(a + b)
+}
+
+// Sub returns a-b, or ErrRange if the difference is out of range
+func (a Amount) Sub(b Amount) (Amount, error) {
+	return checked(a - b)
+}
+
+// Neg returns -a
+func (a Amount) Neg() Amount {
+	return -a
+}
+
+// Abs returns the absolute value of a
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// Sign returns -1, 0 or 1 as a is negative, zero or positive
+func (a Amount) Sign() int {
+	switch {
+	case a < 0:
+		return -1
+	case a > 0:
+		return 1
+	}
+	return 0
+}
+
+// IsZero reports whether a is zero
+func (a Amount) IsZero() bool {
+	return a == 0
+}
+
+// Cmp returns -1, 0 or 1 as a is less than, equal to or greater than b
+func (a Amount) Cmp(b Amount) int {
+	return (a - b).Sign()
+}
+
+func checked(a Amount) (Amount, error) {
+	if a < MinAmount || a > MaxAmount {
+		return 0, ErrRange
+	}
+	return a, nil
+}
+
+// String returns a in decimal with no trailing fractional zeros, e.g.
+// "12.3", "-0.0001" or "100"
+func (a Amount) String() string {
+	return formatDecimal(int64(a), Scale)
+}
+
+// formatDecimal formats n units with the given number of decimal places,
+// trimming trailing fractional zeros
+func formatDecimal(n int64, scale int) string {
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	one := pow10(scale)
+	s := sign + strconv.FormatInt(n/one, 10)
+	if frac := n % one; frac != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%0*d", scale, frac), "0")
+	}
+	return s
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
+
+// MarshalText implements encoding.TextMarshaler using the String form
+func (a Amount) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting any string
+// Parse accepts
+func (a *Amount) UnmarshalText(b []byte) error {
+	parsed, err := Parse(string(b))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding a as a JSON number
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting a JSON number or a
+// string holding one. Like the standard library's decoders it treats null as
+// a no-op.
+func (a *Amount) UnmarshalJSON(b []byte) error {
+	return unmarshalJSON(b, a.UnmarshalText)
+}
+
+// unmarshalJSON decodes a JSON number, or a string holding one, with
+// unmarshalText
+func unmarshalJSON(b []byte, unmarshalText func([]byte) error) error {
+	if string(b) == "null" {
+		return nil
+	}
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		return unmarshalText([]byte(s))
+	}
+	return unmarshalText(b)
+}
+
+// Value implements driver.Valuer, passing a to the database as decimal text
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// Scan implements sql.Scanner for numeric columns, which drivers return as
+// text, and for integer and float columns
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return a.UnmarshalText(v)
+	case string:
+		return a.UnmarshalText([]byte(v))
+	case int64:
+		if v > int64(MaxAmount/unit) || v < int64(MinAmount/unit) {
+			return fmt.Errorf("%w: %d", ErrRange, v)
+		}
+		*a = Amount(v * unit)
+		return nil
+	case float64:
+		// the shortest representation is the decimal the float was parsed
+		// from, for any value with at most 15 significant digits
+		return a.UnmarshalText([]byte(strconv.FormatFloat(v, 'g', -1, 64)))
+	case nil:
+		return fmt.Errorf("money: cannot scan NULL into %T", a)
+	}
+	return fmt.Errorf("money: cannot scan %T into %T", src, a)
+}
@@ -0,0 +1,210 @@
+package money
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+var (
+	_ sql.Scanner              = (*Amount)(nil)
+	_ driver.Valuer            = Amount(0)
+	_ encoding.TextMarshaler   = Amount(0)
+	_ encoding.TextUnmarshaler = (*Amount)(nil)
+	_ json.Marshaler           = Amount(0)
+	_ json.Unmarshaler         = (*Amount)(nil)
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+	}{
+		{"0", 0},
+		{"-0", 0},
+		{"1", 10000},
+		{"12.34", 123400},
+		{"+12.34", 123400},
+		{"-0.0001", -1},
+		{".5", 5000},
+		{"0.10000000", 1000},
+		{"1e3", 10000000},
+		{"2.5e-3", 25},
+		{"00012", 120000},
+		{"0e999999", 0},
+		{"0e9223372036854775807", 0},
+		{"100000000000000000000e-20", 10000},
+		{"99999999999.9999", MaxAmount},
+		{"-99999999999.9999", MinAmount},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Fatalf("Parse(%q): got error %v", tt.in, err)
+		}
+		if got != tt.want {
+			t.Fatalf("Parse(%q) = %d units, want %d", tt.in, got.Units(), tt.want.Units())
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		in   string
+		want error
+	}{
+		{"", ErrSyntax},
+		{"-", ErrSyntax},
+		{".", ErrSyntax},
+		{"1,000", ErrSyntax},
+		{"1_000", ErrSyntax},
+		{" 1", ErrSyntax},
+		{"0x10", ErrSyntax},
+		{"1e", ErrSyntax},
+		{"NaN", ErrSyntax},
+		{"Inf", ErrSyntax},
+		{"0.00001", ErrPrecision},
+		{"1.5E-4", ErrPrecision},
+		{"0.30000000000000004", ErrPrecision},
+		{"100000000000", ErrRange},
+		{"-100000000000", ErrRange},
+		{"1e11", ErrRange},
+		{"1e999999999", ErrRange},
+		{"1e9223372036854775803", ErrRange},
+		{"1e9223372036854775807", ErrRange},
+		{"1e99999999999999999999", ErrRange},
+		{"1e-9223372036854775808", ErrPrecision},
+		{"1e-99999999999999999999", ErrPrecision},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.in); !errors.Is(err, tt.want) {
+			t.Fatalf("Parse(%q): got error %v, want %v", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in   Amount
+		want string
+	}{
+		{0, "0"},
+		{1, "0.0001"},
+		{-1, "-0.0001"},
+		{10000, "1"},
+		{123400, "12.34"},
+		{-123456, "-12.3456"},
+		{MaxAmount, "99999999999.9999"},
+		{MinAmount, "-99999999999.9999"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Fatalf("String(%d units) = %q, want %q", tt.in.Units(), got, tt.want)
+		}
+		if back := MustParse(tt.want); back != tt.in {
+			t.Fatalf("Parse(%q) = %d units, want %d", tt.want, back.Units(), tt.in.Units())
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	a, b := MustParse("0.1"), MustParse("0.2")
+	sum, err := a.Add(b)
+	if err != nil {
+		t.Fatalf("Add: got error %v", err)
+	}
+	if sum != MustParse("0.3") || sum.String() != "0.3" {
+		t.Fatalf("0.1 + 0.2 = %v, want 0.3", sum)
+	}
+	diff, err := a.Sub(b)
+	if err != nil {
+		t.Fatalf("Sub: got error %v", err)
+	}
+	if diff.String() != "-0.1" || diff.Sign() != -1 || diff.Abs() != a || diff.Neg() != a {
+		t.Fatalf("0.1 - 0.2 = %v, want -0.1", diff)
+	}
+	if a.Cmp(b) != -1 || b.Cmp(a) != 1 || a.Cmp(a) != 0 {
+		t.Fatalf("Cmp is inconsistent for %v and %v", a, b)
+	}
+
+	if _, err := MaxAmount.Add(1); !errors.Is(err, ErrRange) {
+		t.Fatalf("MaxAmount + 1: got error %v, want ErrRange", err)
+	}
+	if _, err := MinAmount.Sub(1); !errors.Is(err, ErrRange) {
+		t.Fatalf("MinAmount - 1: got error %v, want ErrRange", err)
+	}
+	if _, err := FromUnits(int64(MaxAmount) + 1); !errors.Is(err, ErrRange) {
+		t.Fatalf("FromUnits: got error %v, want ErrRange", err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type request struct {
+		Amount Amount `json:"amount"`
+	}
+	for _, in := range []string{`{"amount": 12.34}`, `{"amount": "12.34"}`, `{"amount": 1234e-2}`} {
+		var req request
+		if err := json.Unmarshal([]byte(in), &req); err != nil {
+			t.Fatalf("Unmarshal(%s): got error %v", in, err)
+		}
+		if req.Amount != MustParse("12.34") {
+			t.Fatalf("Unmarshal(%s) = %v, want 12.34", in, req.Amount)
+		}
+	}
+
+	b, err := json.Marshal(request{Amount: MustParse("0.3")})
+	if err != nil {
+		t.Fatalf("Marshal: got error %v", err)
+	}
+	if string(b) != `{"amount":0.3}` {
+		t.Fatalf("Marshal = %s, want {\"amount\":0.3}", b)
+	}
+
+	req := request{Amount: 5}
+	if err := json.Unmarshal([]byte(`{"amount": null}`), &req); err != nil || req.Amount != 5 {
+		t.Fatalf("null should leave the amount unchanged: got %v, %v", req.Amount, err)
+	}
+
+	for _, in := range []string{`{"amount": 0.00001}`, `{"amount": "1e12"}`, `{"amount": true}`, `{"amount": "abc"}`, `{"amount": {}}`} {
+		var req request
+		if err := json.Unmarshal([]byte(in), &req); err == nil {
+			t.Fatalf("Expected %s to be rejected", in)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		src  interface{}
+		want Amount
+	}{
+		{[]byte("12.3400"), MustParse("12.34")},
+		{"-0.0001", -1},
+		{int64(7), MustParse("7")},
+		{0.1, MustParse("0.1")},
+	}
+	for _, tt := range tests {
+		var got Amount
+		if err := got.Scan(tt.src); err != nil {
+			t.Fatalf("Scan(%v): got error %v", tt.src, err)
+		}
+		if got != tt.want {
+			t.Fatalf("Scan(%v) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+
+	var a Amount
+	for _, src := range []interface{}{nil, true, int64(100000000000), []byte("1.00001")} {
+		if err := a.Scan(src); err == nil {
+			t.Fatalf("Expected Scan(%v) to fail", src)
+		}
+	}
+
+	v, err := MustParse("-12.5").Value()
+	if err != nil || v != "-12.5" {
+		t.Fatalf("Value = %v, %v, want -12.5", v, err)
+	}
+}
@@ -0,0 +1,158 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	// RateScale is the number of decimal places a Rate holds, matching the
+	// decimal(18,8) columns it is stored in
+	RateScale = 8
+	// RatePrecision is the number of significant digits a Rate holds
+	RatePrecision = 18
+)
+
+// ErrRate is returned for rates that are not positive or do not fit a Rate
+var ErrRate = errors.New("Invalid rate: must be a positive decimal with at most 8 decimal places")
+
+// Rate is an exchange rate: the amount of one currency that one unit of
+// another buys, exact to 8 decimal places and held as a count of 10^-8.
+// The zero value is not a valid rate.
+type Rate int64
+
+// RateFromUnits returns the Rate of n hundred-millionths
+func RateFromUnits(n int64) (Rate, error) {
+	if n <= 0 || n >= pow10(RatePrecision) {
+		return 0, fmt.Errorf("%w: %d units", ErrRate, n)
+	}
+	return Rate(n), nil
+}
+
+// ParseRate parses a positive decimal number in any form Parse accepts
+func ParseRate(s string) (Rate, error) {
+	n, err := parseDecimal(s, RateScale, RatePrecision)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%w: %q", ErrRate, s)
+	}
+	return Rate(n), nil
+}
+
+// MustParseRate is like ParseRate but panics on error. It is intended for
+// constants in tests and configuration.
+func MustParseRate(s string) Rate {
+	r, err := ParseRate(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// ImpliedRate returns the rate at which from converts to to, rounded to 8
+// decimal places. Both must be positive.
+func ImpliedRate(from, to Amount) (Rate, error) {
+	if from.Sign() <= 0 || to.Sign() <= 0 {
+		return 0, fmt.Errorf("%w: %v to %v", ErrRate, from, to)
+	}
+	num := new(big.Int).Mul(big.NewInt(to.Units()), big.NewInt(pow10(RateScale)))
+	q := divRound(num, big.NewInt(from.Units()))
+	if !q.IsInt64() {
+		return 0, fmt.Errorf("%w: %v to %v", ErrRate, from, to)
+	}
+	return RateFromUnits(q.Int64())
+}
+
+// CrossRate returns the rate from one currency to another given the rates
+// from a common base currency to each, rounded to 8 decimal places
+func CrossRate(baseToFrom, baseToTo Rate) (Rate, error) {
+	if baseToFrom <= 0 || baseToTo <= 0 {
+		return 0, fmt.Errorf("%w: %v and %v", ErrRate, baseToFrom, baseToTo)
+	}
+	num := new(big.Int).Mul(big.NewInt(baseToTo.Units()), big.NewInt(pow10(RateScale)))
+	q := divRound(num, big.NewInt(baseToFrom.Units()))
+	if !q.IsInt64() {
+		return 0, fmt.Errorf("%w: %v / %v", ErrRate, baseToTo, baseToFrom)
+	}
+	return RateFromUnits(q.Int64())
+}
+
+// Units returns r as a count of hundred-millionths
+func (r Rate) Units() int64 {
+	return int64(r)
+}
+
+// Convert returns a×r rounded half away from zero to the minor units of
+// to, the currency r converts into. It returns ErrRange if the result does
+// not fit an Amount.
+func (r Rate) Convert(a Amount, to Currency) (Amount, error) {
+	step := pow10(Scale - to.MinorUnits())
+	num := new(big.Int).Mul(big.NewInt(a.Units()), big.NewInt(r.Units()))
+	den := new(big.Int).Mul(big.NewInt(pow10(RateScale)), big.NewInt(step))
+	q := divRound(num, den)
+	if !q.IsInt64() || q.Int64() > MaxAmount.Units()/step || q.Int64() < MinAmount.Units()/step {
+		return 0, fmt.Errorf("%w: %v × %v", ErrRange, a, r)
+	}
+	return Amount(q.Int64() * step), nil
+}
+
+// divRound returns num/den rounded half away from zero. den must be
+// positive.
+func divRound(num, den *big.Int) *big.Int {
+	q, m := new(big.Int).QuoRem(num, den, new(big.Int))
+	if m.Abs(m).Lsh(m, 1).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(int64(num.Sign())))
+	}
+	return q
+}
+
+// String returns r in decimal with no trailing fractional zeros
+func (r Rate) String() string {
+	return formatDecimal(int64(r), RateScale)
+}
+
+// MarshalText implements encoding.TextMarshaler using the String form
+func (r Rate) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting any string
+// ParseRate accepts
+func (r *Rate) UnmarshalText(b []byte) error {
+	parsed, err := ParseRate(string(b))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding r as a JSON number
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting a JSON number or a
+// string holding one
+func (r *Rate) UnmarshalJSON(b []byte) error {
+	return unmarshalJSON(b, r.UnmarshalText)
+}
+
+// Value implements driver.Valuer, passing r to the database as decimal text
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+// Scan implements sql.Scanner for numeric columns
+func (r *Rate) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return r.UnmarshalText(v)
+	case string:
+		return r.UnmarshalText([]byte(v))
+	case nil:
+		return fmt.Errorf("money: cannot scan NULL into %T", r)
+	}
+	return fmt.Errorf("money: cannot scan %T into %T", src, r)
+}
@@ -0,0 +1,95 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseRate(t *testing.T) {
+	for in, want := range map[string]int64{"1": 100000000, "0.92": 92000000, "149.12345678": 14912345678, "1e-8": 1} {
+		got, err := ParseRate(in)
+		if err != nil {
+			t.Fatalf("ParseRate(%q): got error %v", in, err)
+		}
+		if got.Units() != want {
+			t.Fatalf("ParseRate(%q) = %d units, want %d", in, got.Units(), want)
+		}
+		if back := MustParseRate(got.String()); back != got {
+			t.Fatalf("String(%v) does not round trip", got)
+		}
+	}
+	for _, in := range []string{"0", "-1", "0.000000001", "10000000000", "abc", "", "1e9223372036854775803", "1e9223372036854775807", "1e-9223372036854775808"} {
+		if _, err := ParseRate(in); !errors.Is(err, ErrRate) {
+			t.Fatalf("ParseRate(%q): got error %v, want ErrRate", in, err)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		rate   string
+		amount string
+		to     Currency
+		want   string
+	}{
+		{"0.92", "100", "EUR", "92"},
+		{"0.92", "10.01", "EUR", "9.21"},  // 9.2092
+		{"0.92", "10.05", "EUR", "9.25"},  // 9.246
+		{"149.5", "10.01", "JPY", "1496"}, // 1496.495
+		{"149.5", "10.03", "JPY", "1499"}, // 1499.485
+		{"0.5", "0.01", "USD", "0.01"},    // 0.005 rounds away from zero
+		{"0.5", "-0.01", "USD", "-0.01"},  // -0.005 rounds away from zero
+		{"0.30712", "100", "KWD", "30.712"},
+		{"1.1", "12.3456", "CLF", "13.5802"}, // 13.58016
+	}
+	for _, tt := range tests {
+		got, err := MustParseRate(tt.rate).Convert(MustParse(tt.amount), tt.to)
+		if err != nil {
+			t.Fatalf("%s × %s: got error %v", tt.amount, tt.rate, err)
+		}
+		if got != MustParse(tt.want) {
+			t.Fatalf("%s × %s in %v = %v, want %s", tt.amount, tt.rate, tt.to, got, tt.want)
+		}
+	}
+	if _, err := MustParseRate("1000").Convert(MaxAmount, "USD"); !errors.Is(err, ErrRange) {
+		t.Fatalf("Convert out of range: got error %v, want ErrRange", err)
+	}
+}
+
+func TestImpliedRate(t *testing.T) {
+	got, err := ImpliedRate(MustParse("3"), MustParse("1"))
+	if err != nil {
+		t.Fatalf("ImpliedRate: got error %v", err)
+	}
+	if got != MustParseRate("0.33333333") {
+		t.Fatalf("ImpliedRate(3, 1) = %v, want 0.33333333", got)
+	}
+	got, err = ImpliedRate(MustParse("3"), MustParse("2"))
+	if err != nil || got != MustParseRate("0.66666667") {
+		t.Fatalf("ImpliedRate(3, 2) = %v, %v, want 0.66666667", got, err)
+	}
+	for _, pair := range [][2]Amount{{0, 1}, {1, 0}, {-1, 1}} {
+		if _, err := ImpliedRate(pair[0], pair[1]); !errors.Is(err, ErrRate) {
+			t.Fatalf("ImpliedRate(%v, %v): got error %v, want ErrRate", pair[0], pair[1], err)
+		}
+	}
+}
+
+func TestRateJSON(t *testing.T) {
+	var quote struct {
+		Rate Rate `json:"rate"`
+	}
+	for _, in := range []string{`{"rate": 1.0825}`, `{"rate": "1.0825"}`} {
+		if err := json.Unmarshal([]byte(in), &quote); err != nil {
+			t.Fatalf("Unmarshal(%s): got error %v", in, err)
+		}
+		if quote.Rate != MustParseRate("1.0825") {
+			t.Fatalf("Unmarshal(%s) = %v", in, quote.Rate)
+		}
+	}
+	b, err := json.Marshal(quote)
+	if err != nil || string(b) != `{"rate":1.0825}` {
+		t.Fatalf("Marshal = %s, %v", b, err)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"chariot-assessment/pkg/money"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// FieldError reports why one field of a request body is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrorResponse is the body of 422 responses, listing every
+// invalid field of the request
+type ValidationErrorResponse struct {
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields"`
+}
+
+// requestValidator is implemented by request bodies with rules beyond what
+// their fields' types enforce while decoding
+type requestValidator interface {
+	Validate() []FieldError
+}
+
+func writeValidationErrors(w http.ResponseWriter, fields ...FieldError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(ValidationErrorResponse{
+		Error:  "invalid request",
+		Fields: fields,
+	})
+}
+
+// decodeRequest decodes the JSON object in r's body into dst, a pointer to a
+// struct, and validates it. Each field is decoded separately so that every
+// invalid field is reported, together with any errors from dst's Validate
+// method, in a 422 response. Bodies that are not a JSON object are rejected
+// with a 400. It returns false if it wrote an error response.
+func decodeRequest(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	fields, ok := decodeRequestFields(w, r, dst)
+	if !ok {
+		return false
+	}
+	if len(fields) > 0 {
+		writeValidationErrors(w, fields...)
+		return false
+	}
+	return true
+}
+
+// decodeRequestFields is decodeRequest for handlers that check the request
+// against stored data before responding: it returns the invalid fields
+// instead of writing a 422, so that the handler can report its own errors
+// in the same response. It returns false if it wrote a 400.
+func decodeRequestFields(w http.ResponseWriter, r *http.Request, dst interface{}) ([]FieldError, bool) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		fmt.Println("Could not read body", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, false
+	}
+	defer func() {
+		// Drain the request body
+		io.Copy(ioutil.Discard, r.Body)
+		r.Body.Close()
+	}()
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil || raw == nil {
+		if err == nil {
+			err = errors.New("must be a JSON object")
+		}
+		fmt.Println("Could not unmarshal request body", err)
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return nil, false
+	}
+
+	fields := decodeFields(raw, dst)
+	if v, ok := dst.(requestValidator); ok {
+		// fields that failed to decode hold zero values, which would only
+		// be reported again
+		failed := map[string]bool{}
+		for _, f := range fields {
+			failed[f.Field] = true
+		}
+		for _, f := range v.Validate() {
+			if !failed[f.Field] {
+				fields = append(fields, f)
+			}
+		}
+	}
+	return fields, true
+}
+
+// hasField reports whether fields holds an error for the named field
+func hasField(fields []FieldError, name string) bool {
+	for _, f := range fields {
+		if f.Field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeFields decodes each member of raw into the field of the struct dst
+// points to with the matching JSON name, matched case-insensitively as
+// encoding/json does, and returns an error for each one that fails
+func decodeFields(raw map[string]json.RawMessage, dst interface{}) []FieldError {
+	var errs []FieldError
+	v := reflect.ValueOf(dst).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		value, ok := raw[name]
+		if !ok {
+			for key, kv := range raw {
+				if strings.EqualFold(key, name) {
+					value, ok = kv, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(value, v.Field(i).Addr().Interface()); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				err = fmt.Errorf("must be a JSON %s", jsonKind(typeErr.Type))
+			}
+			errs = append(errs, FieldError{Field: name, Message: err.Error()})
+		}
+	}
+	return errs
+}
+
+// jsonKind names the JSON type values of t are decoded from
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "object"
+}
+
+// maxIdempotencyKeyLength is the size of the idempotency_key column
+const maxIdempotencyKeyLength = 100
+
+// validateAmount checks that an amount to move is positive
+func validateAmount(field string, a money.Amount) []FieldError {
+	if a.Sign() <= 0 {
+		return []FieldError{{Field: field, Message: "must be positive"}}
+	}
+	return nil
+}
+
+// validateIdempotencyKey checks that a request has an idempotency key that
+// fits its column
+func validateIdempotencyKey(key string) []FieldError {
+	switch {
+	case key == "":
+		return []FieldError{{Field: "idempotencyKey", Message: "is required"}}
+	case len(key) > maxIdempotencyKeyLength:
+		return []FieldError{{Field: "idempotencyKey", Message: fmt.Sprintf("must be at most %d bytes", maxIdempotencyKeyLength)}}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"chariot-assessment/pkg/id"
+	"chariot-assessment/pkg/money"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// decode runs decodeRequest on body, returning its result and the response
+// it wrote
+func decode(t *testing.T, body string, dst interface{}) (bool, *httptest.ResponseRecorder) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	return decodeRequest(w, r, dst), w
+}
+
+// fieldErrors decodes the fields of a 422 response
+func fieldErrors(t *testing.T, w *httptest.ResponseRecorder) map[string]string {
+	t.Helper()
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("got status %d, want 422: %s", w.Code, w.Body)
+	}
+	var resp ValidationErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Could not decode response %s: %v", w.Body, err)
+	}
+	fields := map[string]string{}
+	for _, f := range resp.Fields {
+		if _, dup := fields[f.Field]; dup {
+			t.Fatalf("%s reported twice: %s", f.Field, w.Body)
+		}
+		fields[f.Field] = f.Message
+	}
+	return fields
+}
+
+func TestDecodeRequestValid(t *testing.T) {
+	account, err := id.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req TransferRequest
+	ok, w := decode(t, `{"amount": "25.5", "IDEMPOTENCYKEY": "k1", "externalAccount": "`+id.AccountID(account).String()+`"}`, &req)
+	if !ok {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+	want := TransferRequest{
+		Amount:          money.MustParse("25.5"),
+		IdempotencyKey:  "k1",
+		ExternalAccount: id.AccountID(account),
+	}
+	if !reflect.DeepEqual(req, want) {
+		t.Fatalf("got %+v, want %+v", req, want)
+	}
+}
+
+func TestDecodeRequestAmounts(t *testing.T) {
+	for _, amount := range []string{`-50`, `0`, `"0.00"`, `"NaN"`, `"Infinity"`, `"-Inf"`, `1e400`, `0.00001`, `"1.23456"`, `100000000000`, `true`, `null`} {
+		var req DepositWithdrawRequest
+		ok, w := decode(t, `{"amount": `+amount+`, "idempotencyKey": "k1"}`, &req)
+		if ok {
+			t.Fatalf("amount %s was accepted", amount)
+		}
+		fields := fieldErrors(t, w)
+		if _, ok := fields["amount"]; !ok || len(fields) != 1 {
+			t.Fatalf("amount %s: got field errors %v, want only amount", amount, fields)
+		}
+	}
+}
+
+func TestDecodeRequestEveryField(t *testing.T) {
+	var req TransferRequest
+	ok, w := decode(t, `{
+		"amount": -1,
+		"externalAccount": "usr_BC5YUGA-AATBBDEINAA",
+		"convertedAmount": "abc",
+		"quoteId": "fxq_BC5YUGA-AATBBDEINAA"
+	}`, &req)
+	if ok {
+		t.Fatal("invalid transfer was accepted")
+	}
+	fields := fieldErrors(t, w)
+	for _, field := range []string{"amount", "idempotencyKey", "externalAccount", "convertedAmount"} {
+		if _, ok := fields[field]; !ok {
+			t.Fatalf("missing error for %s: got %v", field, fields)
+		}
+	}
+	if fields["amount"] != "must be positive" || fields["idempotencyKey"] != "is required" {
+		t.Fatalf("got field errors %v", fields)
+	}
+}
+
+func TestDecodeRequestConflicts(t *testing.T) {
+	var req TransferRequest
+	ok, w := decode(t, `{
+		"amount": 10,
+		"idempotencyKey": "`+strings.Repeat("k", maxIdempotencyKeyLength+1)+`",
+		"externalAccount": "acct_BC5YUGA-AATBBDEINAA",
+		"convertedAmount": 0,
+		"quoteId": "fxq_BC5YUGA-AATBBDEINAA"
+	}`, &req)
+	if ok {
+		t.Fatal("invalid transfer was accepted")
+	}
+	fields := fieldErrors(t, w)
+	if len(fields) != 3 || fields["convertedAmount"] != "must be positive" || fields["quoteId"] == "" || fields["idempotencyKey"] == "" {
+		t.Fatalf("got field errors %v", fields)
+	}
+}
+
+func TestDecodeRequestMalformed(t *testing.T) {
+	for _, body := range []string{``, `[]`, `null`, `{"amount": 1`, `"amount"`} {
+		var req DepositWithdrawRequest
+		ok, w := decode(t, body, &req)
+		if ok || w.Code != http.StatusBadRequest {
+			t.Fatalf("body %q: got %v with status %d, want 400", body, ok, w.Code)
+		}
+	}
+}
+
+func TestConvertTransferReportsEveryField(t *testing.T) {
+	// the sender's amount already failed a check, e.g. the currency's
+	// precision, and the converted amount has too many decimal places
+	converted := money.MustParse("9.123")
+	req := TransferRequest{Amount: money.MustParse("10.005"), ConvertedAmount: &converted}
+	fields := []FieldError{{Field: "amount", Message: "too precise"}}
+	_, _, fields, ok := convertTransfer(httptest.NewRecorder(), nil, req, req.Amount, "USD", "EUR", fields)
+	if !ok {
+		t.Fatal("convertTransfer wrote a response")
+	}
+	if len(fields) != 2 || fields[0].Field != "amount" || fields[1].Field != "convertedAmount" {
+		t.Fatalf("got field errors %v, want amount and convertedAmount", fields)
+	}
+
+	// a field that failed to decode is not reported twice
+	req = TransferRequest{Amount: money.MustParse("10")}
+	fields = []FieldError{{Field: "convertedAmount", Message: "must be a JSON number"}}
+	_, _, fields, _ = convertTransfer(httptest.NewRecorder(), nil, req, req.Amount, "USD", "EUR", fields)
+	if len(fields) != 1 {
+		t.Fatalf("got field errors %v, want only convertedAmount", fields)
+	}
+}